@@ -0,0 +1,148 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"path"
+	"slices"
+	"strings"
+
+	"bloggerout/internal/filename"
+	"bloggerout/internal/takeout/resources"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+)
+
+// convertAlbums writes the blog's albums matching the --albums pattern as Hugo page bundles
+func (bc *blogConverter) convertAlbums(ctx context.Context) {
+	for _, album := range bc.data.Resources.Albums() {
+		if !matchPattern(bc.albumsPattern, album.Title) && !matchPattern(bc.albumsPattern, album.Container) {
+			continue
+		}
+		if !bc.exportsContainer(album.Container) {
+			continue
+		}
+		bc.workers.Submit(func(ctx context.Context) {
+			err := bc.newAlbumConverter(ctx, album)
+			if err != nil {
+				slog.Error("Error converting album", "error", err, "album", album.Title)
+			}
+		})
+	}
+}
+
+// exportsContainer tells if the album or the channel of the container is exported with the blog:
+// its own containers, and the containers of none of the converted blogs with the first one,
+// so each of them is exported once.
+func (bc *blogConverter) exportsContainer(container string) bool {
+	if bc.ownsContainer(container) {
+		return true
+	}
+	for _, blog := range bc.blogs {
+		if isBlogContainer(bc.data.Resources, blog, container) {
+			return false
+		}
+	}
+	return bc.isFirstBlog()
+}
+
+// isFirstBlog tells if the blog is the first of the converted blogs, the one reporting the takeout-wide findings
+func (bc *blogConverter) isFirstBlog() bool {
+	return len(bc.blogs) == 0 || bc.blog == slices.Min(bc.blogs)
+}
+
+// matchPattern checks the name against a selection pattern: '*' for all, or a part of the name
+func matchPattern(pattern string, name string) bool {
+	if pattern == "" {
+		return false
+	}
+	return pattern == "*" || strings.Contains(name, pattern)
+}
+
+func (bc *blogConverter) newAlbumConverter(ctx context.Context, album *resources.Album) error {
+	pc := &postConverter{
+		blogConverter: bc,
+		errors:        make(map[string]int),
+		resources:     make(map[string]*resource),
+	}
+	return pc.convertAlbum(ctx, album)
+}
+
+// convertAlbum writes the album as a page bundle with a gallery of its photos
+func (pc *postConverter) convertAlbum(ctx context.Context, album *resources.Album) error {
+	pc.hp = HugoPost{
+		Blog:  pc.blog,
+		Title: album.Title,
+		Date:  album.Date(),
+		Tags:  []string{"album"},
+		Params: map[string]string{
+			"albumItems": fmt.Sprint(album.TotalItems),
+		},
+	}
+	if !album.End.IsZero() {
+		pc.hp.Params["albumEnd"] = album.End.Format("2006-01-02")
+	}
+
+	destPath, err := prepareFileName(pc.albumPathTmpl, map[string]any{
+		"Blog": filename.Sanitize(pc.hp.Blog),
+		"Date": pc.hp.Date,
+	})
+	if err != nil {
+		return fmt.Errorf("can't prepare album file name: %w", err)
+	}
+	destPath = path.Join(destPath, pc.hp.Date.Format("2006-01-02")+" "+filename.Sanitize(pc.hp.Title))
+
+	pc.pfs, err = openBundle(pc.rfs, destPath)
+	if err != nil {
+		return fmt.Errorf("can't create Hugo album directory: %w", err)
+	}
+
+	sb := strings.Builder{}
+	if album.Description != "" {
+		sb.WriteString(safeText(album.Description))
+		sb.WriteString("\n\n")
+	}
+	pc.renderGallery(ctx, &sb, album)
+	pc.hp.content = sb.String()
+
+	for e := range pc.errors {
+		pc.hp.Tags = append(pc.hp.Tags, e)
+	}
+
+	dst, err := pc.pfs.Create("index.md")
+	if err != nil {
+		return fmt.Errorf("can't create Hugo album file: %w", err)
+	}
+	defer dst.Close()
+
+	err = pc.Write(dst, pc.hp)
+	if err != nil {
+		return fmt.Errorf("can't write Hugo album file: %w", err)
+	}
+	slog.Info("album converted", "blog", pc.hp.Blog, "date", pc.hp.Date, "title", pc.hp.Title)
+	return nil
+}
+
+// renderGallery copies the album's photos and videos into the current page bundle,
+// and renders them ordered by capture time inside a gallery shortcode
+func (pc *postConverter) renderGallery(ctx context.Context, w converter.Writer, album *resources.Album) {
+	w.WriteString("{{< gallery >}}\n")
+	for _, r := range pc.data.Resources.SearchContainer(album.Container) {
+		if resources.IsVideo(r.Name()) {
+			_ = pc.renderVideo(ctx, w, video{
+				Resource: r,
+				Source:   r.Name(),
+				Name:     filename.Sanitize(r.Name()),
+				Caption:  r.Metadata().Description,
+			})
+			continue
+		}
+		if !resources.IsImage(r.Name()) {
+			// the blog's folder shares the container name with the blog's album
+			continue
+		}
+		_ = pc.renderTakeoutImage(ctx, w, r, "")
+	}
+	w.WriteString("{{< /gallery >}}\n")
+}
@@ -0,0 +1,132 @@
+package convert
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+	"bloggerout/internal/worker"
+)
+
+func TestConvertAlbums(t *testing.T) {
+	gif := []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+	vfs := fstest.MapFS{
+		"Albums/Trip/beach.gif":      &fstest.MapFile{Data: gif},
+		"Photos/Trip/mountain.gif":   &fstest.MapFile{Data: gif},
+		"Albums/Other/other.gif":     &fstest.MapFile{Data: gif},
+		"Albums/Blog/uploaded.gif":   &fstest.MapFile{Data: gif},
+		"Photos/Blog 2012/party.gif": &fstest.MapFile{Data: gif},
+	}
+	rs := resources.New()
+	date := time.Date(2012, 8, 1, 0, 0, 0, 0, time.UTC)
+	albums := []*resources.Album{
+		{Title: "Trip", Description: "Day one {{< gallery >}}", Container: "Blogger/Trip", Start: date},
+		{Title: "Trip", Container: "Google Photos/Trip", Start: date.AddDate(0, 1, 0)},
+		{Title: "Other", Container: "Blogger/Other", Start: date},
+		{Title: "Blog", Container: "Blogger/Blog", Start: date},
+		{Title: "Blog", Container: "Google Photos/Blog 2012", Start: date.AddDate(0, 2, 0)},
+	}
+	for _, a := range albums {
+		source, folder, _ := strings.Cut(a.Container, "/")
+		dir := "Albums/" + folder
+		if source == resources.PhotosSource {
+			dir = "Photos/" + folder
+		}
+		entries, err := fs.ReadDir(vfs, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			rs.Add(vfs, a.Container, dir, e, &resources.ResourceMetadata{Filename: e.Name(), CreationTimestamp: a.Start})
+		}
+		rs.AddAlbum(a)
+	}
+
+	// each album is exported once, by its blog or by the first blog
+	testCases := []struct {
+		blog  string
+		pages []string
+	}{
+		{"Blog", []string{"2012-08-01 Trip", "2012-09-01 Trip", "2012-08-01 Blog", "2012-10-01 Blog"}},
+		{"Other", []string{"2012-08-01 Other"}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.blog, func(t *testing.T) {
+			rfs, err := os.OpenRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer rfs.Close()
+			bc := &blogConverter{
+				Convert: &Convert{
+					blogs:         []string{"Other", "Blog"},
+					albumsPattern: "*",
+					albumPathTmpl: template.Must(template.New("albumPath").Parse("/content/albums/")),
+				},
+				data:    &takeout.Takeout{Resources: rs},
+				blog:    tc.blog,
+				workers: worker.NewWorkerPool(2),
+				report:  newConversionReport(),
+				rfs:     rfs,
+			}
+			bc.workers.Start(context.Background())
+			bc.convertAlbums(context.Background())
+			bc.workers.Stop()
+
+			entries, err := os.ReadDir(rfs.Name() + "/content/albums")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := []string{}
+			for _, e := range entries {
+				got = append(got, e.Name())
+			}
+			if len(got) != len(tc.pages) {
+				t.Fatalf("albums %v; want %v", got, tc.pages)
+			}
+			for _, page := range tc.pages {
+				if _, err := os.Stat(rfs.Name() + "/content/albums/" + page + "/index.md"); err != nil {
+					t.Errorf("album %s not exported: %v", page, err)
+				}
+			}
+		})
+	}
+
+	// the Blogger and Google Photos albums of the same name stay apart
+	rfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+	bc := &blogConverter{
+		Convert: &Convert{
+			blogs:         []string{"Blog"},
+			albumPathTmpl: template.Must(template.New("albumPath").Parse("/content/albums/")),
+		},
+		data:   &takeout.Takeout{Resources: rs},
+		blog:   "Blog",
+		report: newConversionReport(),
+		rfs:    rfs,
+	}
+	err = bc.newAlbumConverter(context.Background(), rs.SearchAlbum("Blogger/Trip"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(rfs.Name() + "/content/albums/2012-08-01 Trip/index.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), `src="beach.gif"`) || strings.Contains(string(page), "mountain.gif") {
+		t.Errorf("unexpected gallery:\n%s", page)
+	}
+	if strings.Contains(string(page), "{{< gallery >}}\n\n") || !strings.Contains(string(page), "Day one {&#123;< gallery >}}") {
+		t.Errorf("description not escaped:\n%s", page)
+	}
+}
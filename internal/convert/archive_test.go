@@ -0,0 +1,117 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"bloggerout/internal/downloader"
+)
+
+func TestImageDownloadArchiveFallback(t *testing.T) {
+	gif := []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wayback/available":
+			if !strings.HasSuffix(r.URL.Query().Get("url"), "/archived.gif") {
+				fmt.Fprint(w, `{"archived_snapshots": {}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"archived_snapshots": {"closest": {"available": true, "url": "%s/web/20100101000000/http://gone.example/archived.gif", "status": "200"}}}`, srv.URL)
+		case "/web/20100101000000id_/http://gone.example/archived.gif":
+			w.Write(gif)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	o := downloader.DefaultOptions()
+	o.Retries = 0
+	o.ArchiveURL = srv.URL
+	testCases := []struct {
+		name     string
+		fallback bool
+		wantErr  bool
+	}{
+		{"archived.gif", true, false},
+		{"archived.gif", false, true},
+		{"lost.gif", true, true},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.name, tc.fallback), func(t *testing.T) {
+			pfs, err := os.OpenRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pfs.Close()
+			pc := &postConverter{
+				blogConverter: &blogConverter{
+					Convert:    &Convert{archiveFallback: tc.fallback},
+					downloader: downloader.NewDownloader(o),
+					report:     newConversionReport(),
+				},
+				hp:        HugoPost{Title: "Post"},
+				errors:    map[string]int{},
+				resources: map[string]*resource{},
+				pfs:       pfs,
+			}
+			err = pc.ImageDownload(context.Background(), &resource{Source: srv.URL + "/" + tc.name, Name: tc.name})
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("ImageDownload() error = %v; want error %v", err, tc.wantErr)
+			}
+			restored := len(pc.report.sections[archiveSection])
+			if tc.wantErr {
+				if restored != 0 {
+					t.Errorf("unexpected report %v", pc.report.sections)
+				}
+				return
+			}
+			if restored != 1 || !strings.Contains(pc.report.sections[archiveSection][0].Message, "/web/20100101000000/") {
+				t.Errorf("unexpected report %v", pc.report.sections)
+			}
+			if _, err := pfs.Stat(tc.name); err != nil {
+				t.Errorf("image not saved: %v", err)
+			}
+		})
+	}
+}
+
+func TestImageDownloadRejected(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `<html><body><form><input type="password"></form></body></html>`)
+	}))
+	defer srv.Close()
+
+	pfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pfs.Close()
+	o := downloader.DefaultOptions()
+	o.Retries = 0
+	pc := &postConverter{
+		blogConverter: &blogConverter{
+			Convert:    &Convert{},
+			downloader: downloader.NewDownloader(o),
+			report:     newConversionReport(),
+		},
+		hp:        HugoPost{Title: "Post"},
+		errors:    map[string]int{},
+		resources: map[string]*resource{},
+		pfs:       pfs,
+	}
+	err = pc.ImageDownload(context.Background(), &resource{Source: srv.URL + "/photo.jpg", Name: "photo.jpg"})
+	if err == nil {
+		t.Fatal("ImageDownload() accepted a login page")
+	}
+	entries := pc.report.sections[rejectedSection]
+	if len(entries) != 1 || entries[0].Message != "login page instead of an image: "+srv.URL+"/photo.jpg" {
+		t.Errorf("unexpected report %v", pc.report.sections)
+	}
+}
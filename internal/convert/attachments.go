@@ -0,0 +1,141 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+
+	"bloggerout/internal/downloader"
+	"bloggerout/internal/filename"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+const attachmentsSection = "Attachments not downloaded"
+
+// attachmentExts are the extensions of the documents and audio files worth a download attempt
+var attachmentExts = map[string]bool{
+	".pdf": true, ".doc": true, ".docx": true, ".xls": true, ".xlsx": true, ".ppt": true, ".pptx": true,
+	".odt": true, ".ods": true, ".odp": true, ".rtf": true,
+	".mp3": true, ".m4a": true, ".ogg": true, ".wav": true, ".flac": true,
+}
+
+// isAttachmentLink tells if the link may point to a document or an audio file:
+// a file with a known extension, or a file hosted by Google Sites, Drive or Docs
+func isAttachmentLink(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if attachmentExts[strings.ToLower(path.Ext(u.Path))] {
+		return true
+	}
+	switch u.Hostname() {
+	case "sites.google.com", "drive.google.com", "docs.google.com":
+		return true
+	}
+	return u.Query().Has("attredirects")
+}
+
+// renderAttachment downloads the document or audio file of the link into the page bundle.
+// The MP3 files are rendered with the audio shortcode, the link to the others is replaced by the local copy.
+// It returns false when the link is kept as is.
+func (pc *postConverter) renderAttachment(ctx context.Context, w converter.Writer, node *html.Node, href string) bool {
+	name, mediaType, ok := pc.downloadAttachment(ctx, href)
+	if !ok {
+		return false
+	}
+	if mediaType == "audio/mpeg" {
+		sb := strings.Builder{}
+		sb.WriteString("{{< media/audio src=")
+		sb.WriteString(safeAttribute(name))
+		if text := linkText(node); text != "" && text != href {
+			sb.WriteString(" caption=")
+			sb.WriteString(safeAttribute(text))
+		}
+		sb.WriteString(" >}}\n")
+		w.WriteString(sb.String())
+		return true
+	}
+	if linkText(node) == "" {
+		setText(node, name)
+	}
+	setAttribute(node, "href", (&url.URL{Path: name}).EscapedPath())
+	return false
+}
+
+// downloadAttachment saves the attachment in the page bundle, once per post, and returns its file name.
+// The failures other than a content that isn't a document are reported.
+func (pc *postConverter) downloadAttachment(ctx context.Context, href string) (string, string, bool) {
+	if a, ok := pc.attached[href]; ok {
+		return a.Name, a.ContentType, true
+	}
+	buf := bytes.Buffer{}
+	a, err := pc.downloader.DownloadAttachment(ctx, href, pc.attachmentMaxSize, &buf)
+	if err != nil {
+		var rejected *downloader.RejectedError
+		switch {
+		case errors.Is(err, downloader.ErrNotAttachment):
+		case errors.As(err, &rejected):
+			pc.report.add(attachmentsSection, pc.hp.Title, rejected.Reason+": "+href)
+		default:
+			pc.report.add(attachmentsSection, pc.hp.Title, err.Error())
+		}
+		return "", "", false
+	}
+
+	var saved bool
+	a.Name, saved, err = pc.attachmentName(filename.Sanitize(a.Name), buf.Bytes())
+	if err == nil && !saved {
+		var f *os.File
+		f, err = pc.pfs.Create(a.Name)
+		if err == nil {
+			_, err = buf.WriteTo(f)
+			if err1 := f.Close(); err == nil {
+				err = err1
+			}
+		}
+	}
+	if err != nil {
+		slog.Error("Failed to save attachment", "file", a.Name, "error", err)
+		pc.report.add(attachmentsSection, pc.hp.Title, fmt.Sprintf("can't save %s: %s", href, err))
+		return "", "", false
+	}
+	if pc.attached == nil {
+		pc.attached = map[string]downloader.Attachment{}
+	}
+	pc.attached[href] = a
+	return a.Name, a.ContentType, true
+}
+
+// attachmentName makes the name unique among the post's attachments and the other files of the page bundle.
+// A file of the same content, saved by a previous conversion, is reused: saved is true.
+func (pc *postConverter) attachmentName(name string, content []byte) (unique string, saved bool, err error) {
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	unique = name
+	for i := 2; ; i++ {
+		used := false
+		for _, a := range pc.attached {
+			used = used || a.Name == unique
+		}
+		if !used {
+			b, err := os.ReadFile(path.Join(pc.pfs.Name(), unique))
+			switch {
+			case errors.Is(err, os.ErrNotExist):
+				return unique, false, nil
+			case err != nil:
+				return "", false, err
+			case bytes.Equal(b, content):
+				return unique, true, nil
+			}
+		}
+		unique = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
@@ -0,0 +1,131 @@
+package convert
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"bloggerout/internal/downloader"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+)
+
+func TestAttachments(t *testing.T) {
+	pdf := []byte("%PDF-1.4 minutes")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/docs/minutes.pdf", "/docs/index.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(pdf)
+		case "/music/song.mp3":
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(pdf)
+		case "/docs/huge.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(bytes.Repeat(pdf, 100))
+		case "/docs/viewer.pdf":
+			fmt.Fprint(w, "<html></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name   string
+		html   string
+		want   string
+		file   string // file saved in the bundle
+		report string
+	}{
+		{"pdf", `<a href="%s/docs/minutes.pdf">the minutes</a>`, `[the minutes](minutes.pdf)`, "minutes.pdf", ""},
+		{"mp3", `<a href="%s/music/song.mp3">Our song</a>`, `{{< media/audio src="song.mp3" caption="Our song" >}}`, "song.mp3", ""},
+		{"html", `<a href="%s/docs/viewer.pdf">viewer</a>`, `[viewer](%s/docs/viewer.pdf)`, "", ""},
+		{"huge", `<a href="%s/docs/huge.pdf">huge</a>`, `[huge](%s/docs/huge.pdf)`, "", "size of 1600 bytes larger than the limit of 1000 bytes: %s/docs/huge.pdf"},
+		{"page", `<a href="%s/docs/">docs</a>`, `[docs](%s/docs/)`, "", ""},
+		{"bundle file", `<a href="%s/docs/index.pdf">index</a>`, `[index](index-2.pdf)`, "index-2.pdf", ""},
+	}
+	o := downloader.DefaultOptions()
+	o.Retries = 0
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pfs, err := os.OpenRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pfs.Close()
+			if err := os.WriteFile(pfs.Name()+"/index.pdf", []byte("image"), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			pc := &postConverter{
+				blogConverter: &blogConverter{
+					Convert:    &Convert{attachments: true, attachmentMaxSize: 1000},
+					downloader: downloader.NewDownloader(o),
+					report:     newConversionReport(),
+				},
+				hp:  HugoPost{Title: "Post"},
+				pfs: pfs,
+			}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("a", converter.TagTypeInline, pc.anchorHandler, converter.PriorityEarly+10)
+
+			got, err := mdc.ConvertString(strings.ReplaceAll(tc.html, "%s", srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := strings.ReplaceAll(tc.want, "%s", srv.URL); got != want {
+				t.Errorf("got %q; want %q", got, want)
+			}
+			if tc.file != "" {
+				b, err := os.ReadFile(pfs.Name() + "/" + tc.file)
+				if err != nil || !bytes.Equal(b, pdf) {
+					t.Errorf("attachment not saved: %v", err)
+				}
+			}
+			entries := pc.report.sections[attachmentsSection]
+			switch {
+			case tc.report == "" && len(entries) > 0:
+				t.Errorf("unexpected report %v", entries)
+			case tc.report != "" && (len(entries) != 1 || entries[0].Message != strings.ReplaceAll(tc.report, "%s", srv.URL)):
+				t.Errorf("unexpected report %v", entries)
+			}
+
+			// a new conversion reuses the saved attachment
+			pc.attached = nil
+			again, err := mdc.ConvertString(strings.ReplaceAll(tc.html, "%s", srv.URL))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if again != got {
+				t.Errorf("second conversion gives %q; want %q", again, got)
+			}
+		})
+	}
+}
+
+func TestIsAttachmentLink(t *testing.T) {
+	testCases := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.com/files/Report.PDF", true},
+		{"http://example.com/podcast/episode1.mp3", true},
+		{"https://sites.google.com/site/club/news/minutes?attredirects=0", true},
+		{"https://drive.google.com/uc?export=download&id=abc", true},
+		{"https://example.com/page.html", false},
+		{"ftp://example.com/file.pdf", false},
+	}
+	for _, tc := range testCases {
+		u, _ := url.Parse(tc.link)
+		if got := isAttachmentLink(u); got != tc.want {
+			t.Errorf("isAttachmentLink(%q) = %v; want %v", tc.link, got, tc.want)
+		}
+	}
+}
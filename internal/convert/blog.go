@@ -14,12 +14,13 @@ import (
 	"text/template"
 
 	"bloggerout/internal/downloader"
+	"bloggerout/internal/filename"
 	"bloggerout/internal/takeout"
 	"bloggerout/internal/takeout/blogger"
 	"bloggerout/internal/worker"
 )
 
-//go:embed _shortcodes/*
+//go:embed _shortcodes/* _partials/*
 var shortCodesFS embed.FS
 
 type blogConverter struct {
@@ -28,43 +29,60 @@ type blogConverter struct {
 	blog       string
 	workers    *worker.WorkerPool
 	downloader *downloader.Downloader
-	report     HugoPost
+	report     *conversionReport
+	store      *mediaStore // shared media store, nil when images are stored in the post bundles
+	links      *linkSet    // external links of the posts, for the link check pass
 	rfs        *os.Root
 }
 
-func newBlogConverter(ctx context.Context, c *Convert, data *takeout.Takeout, blog string) error {
+func newBlogConverter(ctx context.Context, c *Convert, data *takeout.Takeout, blog string) (*blogConverter, error) {
 	blogPath, err := prepareFileName(c.hugoPathTmpl, map[string]string{"Blog": blog})
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	_, err = os.Stat(blogPath)
 	if err != nil {
 		if !os.IsNotExist(err) {
-			return err
+			return nil, err
 		}
 		err := os.MkdirAll(blogPath, 0o755)
 		if err != nil {
-			return err
+			return nil, err
 		}
 	}
 
 	rfs, err := os.OpenRoot(blogPath)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	err = copyShortCodes(rfs, shortCodesFS, "layouts")
 
+	var store *mediaStore
+	if c.imagePathTmpl != nil {
+		storePath, err := prepareFileName(c.imagePathTmpl, map[string]string{"Blog": filename.Sanitize(blog)})
+		if err != nil {
+			return nil, err
+		}
+		store, err = newMediaStore(rfs, storePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	bc := &blogConverter{
 		Convert:    c,
 		data:       data,
 		blog:       blog,
 		workers:    worker.NewWorkerPool(10),
-		downloader: downloader.NewDownloader(),
+		downloader: downloader.NewDownloader(c.download),
 		rfs:        rfs,
+		report:     newConversionReport(),
+		store:      store,
+		links:      newLinkSet(),
 	}
-	return bc.convertBlog(ctx, blog, data.Blogger.Blogs[blog])
+	return bc, bc.convertBlog(ctx, blog, data.Blogger.Blogs[blog])
 }
 
 func (bc *blogConverter) convertBlog(ctx context.Context, blog string, blogData *blogger.Blog) error {
@@ -86,6 +104,12 @@ func (bc *blogConverter) convertBlog(ctx context.Context, blog string, blogData
 			})
 		}
 	}
+	if bc.albumsPattern != "" {
+		bc.convertAlbums(ctx)
+	}
+	if bc.channelsPattern != "" {
+		bc.convertChannels(ctx)
+	}
 	return nil
 }
 
@@ -125,6 +149,21 @@ func mkDirAll(rfs *os.Root, dirName string) error {
 	return nil
 }
 
+// openBundle creates the page bundle directory when needed, and returns its root
+func openBundle(rfs *os.Root, destPath string) (*os.Root, error) {
+	_, err := rfs.Stat(destPath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		err = mkDirAll(rfs, destPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rfs.OpenRoot(destPath)
+}
+
 // copyShortCodes copies the shortcodes from the source code to the destination.
 func copyShortCodes(dest *os.Root, src fs.FS, destPath string) error {
 	err := dest.Mkdir(destPath, 0o755)
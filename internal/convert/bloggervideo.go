@@ -0,0 +1,146 @@
+package convert
+
+import (
+	"context"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"bloggerout/internal/filename"
+	"bloggerout/internal/takeout/resources"
+
+	"github.com/JohannesKaufmann/dom"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// bloggerVideoTolerance is the time between the upload of a video and the publication of the post
+const bloggerVideoTolerance = 7 * 24 * time.Hour
+
+const bloggerVideosSection = "Blogger videos matched by date"
+
+var backgroundImage = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)`)
+
+// bloggerUpload describes a video uploaded to Blogger, as known by its embed
+type bloggerUpload struct {
+	thumbnail string // URL of the thumbnail, empty when unknown
+	size      int64  // size of the video file in bytes, 0 when unknown
+}
+
+// bloggerVideo recognizes the videos uploaded to Blogger, and returns their thumbnail URL
+// and file size when known:
+//
+//	<iframe src="https://www.blogger.com/video.g?token=AD6v5dw..." data-thumbnail-src="https://...">
+//	<iframe class="BLOGGER-video-68f1b1d9b4e5f283-3374" style="background-image: url(https://...)">
+//	<object class="BLOGGER-object-element" data="//www.blogger.com/img/videoplayer.swf?videoUrl=...&thumbnailUrl=...">
+//
+// The size is the clen parameter of the player's googlevideo.com URL.
+func bloggerVideo(node *html.Node) (upload bloggerUpload, ok bool) {
+	upload.thumbnail = dom.GetAttributeOr(node, "data-thumbnail-src", "")
+	if upload.thumbnail == "" {
+		if m := backgroundImage.FindStringSubmatch(dom.GetAttributeOr(node, "style", "")); m != nil {
+			upload.thumbnail = m[1]
+		}
+	}
+
+	for _, class := range strings.Fields(dom.GetAttributeOr(node, "class", "")) {
+		if strings.HasPrefix(class, "BLOGGER-video-") || class == "BLOGGER-object-element" || class == "b-uploaded" {
+			ok = true
+		}
+	}
+
+	src := dom.GetAttributeOr(node, "src", "")
+	if dom.NodeName(node) != "iframe" {
+		src = embedSource(node)
+	}
+	if u, err := url.Parse(src); err == nil && strings.HasSuffix(u.Hostname(), "blogger.com") {
+		switch {
+		case u.Path == "/video.g":
+			ok = true
+		case strings.HasSuffix(u.Path, "videoplayer.swf"):
+			ok = true
+			if t := u.Query().Get("thumbnailUrl"); t != "" && upload.thumbnail == "" {
+				upload.thumbnail = t
+			}
+			if v, err := url.Parse(u.Query().Get("videoUrl")); err == nil {
+				upload.size, _ = strconv.ParseInt(v.Query().Get("clen"), 10, 64)
+			}
+		}
+	}
+	return upload, ok
+}
+
+// genericThumbnail matches the thumbnail names unrelated to the video: 1.jpg, default.jpg, hqdefault.jpg...
+var genericThumbnail = regexp.MustCompile(`^(\d+|(hq|mq|sd|maxres)?default)$`)
+
+// searchBloggerVideo looks for the uploaded video among the blog's own media, not yet used by the post:
+// the video of the file size given by the player, a video named like the thumbnail,
+// or the video uploaded around the post's date. The last one is a guess.
+func (pc *postConverter) searchBloggerVideo(upload bloggerUpload) (r *resources.Resource, guessed bool) {
+	candidates := []*resources.Resource{}
+	for _, container := range pc.blogContainers() {
+		for _, r := range pc.data.Resources.SearchContainer(container) {
+			if resources.IsVideo(r.Name()) && pc.resources[filename.Sanitize(r.Name())] == nil &&
+				(upload.size == 0 || r.SizeBytes() == upload.size) {
+				candidates = append(candidates, r)
+			}
+		}
+	}
+	if upload.size > 0 && len(candidates) > 0 {
+		return candidates[0], false
+	}
+
+	if u, err := url.Parse(upload.thumbnail); err == nil && upload.thumbnail != "" {
+		// the thumbnail is named after the video: .../VID_20120501.mp4.jpg or .../VID_20120501.jpg
+		name := path.Base(u.Path)
+		for name != "" && path.Ext(name) != "" {
+			name = strings.TrimSuffix(name, path.Ext(name))
+			if genericThumbnail.MatchString(name) {
+				break
+			}
+			for _, r := range candidates {
+				if r.Name() == name || strings.TrimSuffix(r.Name(), path.Ext(r.Name())) == name {
+					return r, false
+				}
+			}
+		}
+	}
+
+	var best *resources.Resource
+	for _, r := range candidates {
+		delta := pc.hp.Date.Sub(r.CaptureTime()).Abs()
+		if delta > bloggerVideoTolerance {
+			continue
+		}
+		if best == nil || delta < pc.hp.Date.Sub(best.CaptureTime()).Abs() ||
+			delta == pc.hp.Date.Sub(best.CaptureTime()).Abs() && r.SizeBytes() > best.SizeBytes() {
+			best = r
+		}
+	}
+	return best, best != nil
+}
+
+// renderBloggerVideo renders a video uploaded to Blogger with the video found in the takeout.
+// The videos found by their date only are listed in the report.
+func (pc *postConverter) renderBloggerVideo(ctx context.Context, w converter.Writer, upload bloggerUpload) converter.RenderStatus {
+	r, guessed := pc.searchBloggerVideo(upload)
+	if r == nil {
+		pc.log(w, CONTENT_LOST, "video uploaded to Blogger not found in the takeout")
+		return converter.RenderSuccess
+	}
+	if guessed {
+		pc.report.add(bloggerVideosSection, pc.hp.Title, r.Container()+"/"+r.Name()+" ("+r.CaptureTime().Format("2006-01-02 15:04")+")")
+	}
+	v := video{
+		Resource: r,
+		Source:   r.Name(),
+		Name:     filename.Sanitize(r.Name()),
+		Caption:  r.Metadata().Description,
+	}
+	pc.resources[v.Name] = &resource{Resource: r, Source: v.Source, Name: v.Name}
+	_ = pc.renderVideo(ctx, w, v)
+	return converter.RenderSuccess
+}
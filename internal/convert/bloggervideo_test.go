@@ -0,0 +1,98 @@
+package convert
+
+import (
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+)
+
+func TestBloggerVideo(t *testing.T) {
+	date := time.Date(2012, 5, 1, 12, 0, 0, 0, time.UTC)
+	vfs := fstest.MapFS{
+		"Blog/VID_0001.mp4":   &fstest.MapFile{Data: []byte("first")},
+		"Blog/VID_0002.mp4":   &fstest.MapFile{Data: []byte("second")},
+		"Blog/1.mp4":          &fstest.MapFile{Data: []byte("old")},
+		"Photos/VID_0003.mp4": &fstest.MapFile{Data: []byte("another album")},
+	}
+	dates := map[string]time.Time{
+		"VID_0001.mp4": date,
+		"VID_0002.mp4": date.Add(time.Hour),
+		"1.mp4":        date.AddDate(0, -1, 0),
+		"VID_0003.mp4": date,
+	}
+	rs := resources.New()
+	for _, container := range []string{"Blog", "Photos"} {
+		entries, err := fs.ReadDir(vfs, container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			rs.Add(vfs, container, container, e, &resources.ResourceMetadata{
+				CreationTimestamp: dates[e.Name()],
+				SizeBytes:         int64(len(vfs[container+"/"+e.Name()].Data)),
+			})
+		}
+	}
+
+	testCases := []struct {
+		name    string
+		html    string
+		want    []string
+		guesses int // videos matched by date
+	}{
+		{"token", `<iframe class="b-hbp-video b-uploaded" src="https://www.blogger.com/video.g?token=AD6v5dw"></iframe>`, []string{"VID_0001.mp4"}, 1},
+		{"thumbnail", `<iframe src="https://www.blogger.com/video.g?token=AD6v5dw" data-thumbnail-src="https://lh3.googleusercontent.com/x/s320/VID_0002.jpg"></iframe>`, []string{"VID_0002.mp4"}, 0},
+		{"generic_thumbnail", `<iframe src="https://www.blogger.com/video.g?token=AD6v5dw" data-thumbnail-src="https://lh3.googleusercontent.com/x/s320/1.jpg"></iframe>`, []string{"VID_0001.mp4"}, 1},
+		{"two_videos", `<iframe class="BLOGGER-video-68f1b1d9b4e5f283-3374" src="https://www.youtube.com/get_player"></iframe><iframe class="BLOGGER-video-68f1b1d9b4e5f283-3375" src="https://www.youtube.com/get_player"></iframe>`, []string{"VID_0001.mp4", "VID_0002.mp4"}, 2},
+		{"object", `<object class="BLOGGER-object-element" data="//www.blogger.com/img/videoplayer.swf?videoUrl=http%3A%2F%2Fv.googlevideo.com%2F&thumbnailUrl=http%3A%2F%2Fi.ytimg.com%2Fvi%2Fab%2F0.jpg"></object>`, []string{"VID_0001.mp4"}, 1},
+		{"size", `<object class="BLOGGER-object-element" data="//www.blogger.com/img/videoplayer.swf?videoUrl=http%3A%2F%2Fv.googlevideo.com%2Fvideoplayback%3Fclen%3D6&thumbnailUrl=http%3A%2F%2Fi.ytimg.com%2Fvi%2Fab%2F0.jpg"></object>`, []string{"VID_0002.mp4"}, 0},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pfs, err := os.OpenRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pfs.Close()
+			pc := &postConverter{
+				blogConverter: &blogConverter{Convert: &Convert{}, blog: "Blog", data: &takeout.Takeout{Resources: rs}, report: newConversionReport()},
+				hp:            HugoPost{Title: "Post", Date: date},
+				errors:        map[string]int{},
+				resources:     map[string]*resource{},
+				pfs:           pfs,
+			}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("iframe", converter.TagTypeBlock, pc.iFrameHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("object", converter.TagTypeBlock, pc.videoObjectHandler, converter.PriorityEarly)
+
+			got, err := mdc.ConvertString(postBody(tc.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Count(got, "{{< media/video") != len(tc.want) {
+				t.Errorf("unexpected result: %s", got)
+			}
+			for _, name := range tc.want {
+				if !strings.Contains(got, `src="`+name+`"`) {
+					t.Errorf("%s not rendered: %s", name, got)
+				}
+				if _, err := pfs.Stat(name); err != nil {
+					t.Errorf("%s not copied: %v", name, err)
+				}
+			}
+			if got := len(pc.report.sections[bloggerVideosSection]); got != tc.guesses {
+				t.Errorf("%d videos reported as matched by date; want %d", got, tc.guesses)
+			}
+		})
+	}
+}
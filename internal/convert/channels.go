@@ -0,0 +1,186 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"bloggerout/internal/filename"
+	"bloggerout/internal/takeout/resources"
+	"bloggerout/internal/takeout/youtube"
+)
+
+const privateVideosSection = "Private YouTube videos"
+
+// convertChannels writes the YouTube channels matching the --channels pattern as Hugo sections,
+// with a page bundle per video. Each channel is exported once, like the albums.
+func (bc *blogConverter) convertChannels(ctx context.Context) {
+	if bc.data.YouTube == nil {
+		return
+	}
+	for _, channel := range bc.data.YouTube.Channels() {
+		if !matchPattern(bc.channelsPattern, channel.Title) && !matchPattern(bc.channelsPattern, channel.ID) {
+			continue
+		}
+		if !bc.exportsContainer(resources.ContainerKey(resources.YouTubeSource, channel.ID)) {
+			continue
+		}
+		videos := channelVideos(channel)
+		if len(videos) == 0 {
+			continue
+		}
+		sectionPath, err := bc.writeChannelSection(channel, videoDate(videos[len(videos)-1]))
+		if err != nil {
+			slog.Error("Error converting channel", "error", err, "channel", channel.Title)
+			continue
+		}
+		for _, v := range videos {
+			bc.workers.Submit(func(ctx context.Context) {
+				err := bc.newChannelVideoConverter(ctx, channel, sectionPath, v)
+				if err != nil {
+					slog.Error("Error converting video", "error", err, "channel", channel.Title, "video", v.Title)
+				}
+			})
+		}
+	}
+}
+
+// channelVideos returns the channel's videos having a file in the takeout, ordered by date
+func channelVideos(channel youtube.Channel) []*youtube.Video {
+	l := []*youtube.Video{}
+	for _, id := range slices.Sorted(maps.Keys(channel.Videos)) {
+		if v := channel.Videos[id]; v.Resource != nil {
+			l = append(l, v)
+		}
+	}
+	slices.SortStableFunc(l, func(a, b *youtube.Video) int {
+		return videoDate(a).Compare(videoDate(b))
+	})
+	return l
+}
+
+// videoDate gives the publication date of the video, or its creation date when not published
+func videoDate(v *youtube.Video) time.Time {
+	if !v.Published.IsZero() {
+		return v.Published
+	}
+	return v.Created
+}
+
+// writeChannelSection writes the _index.md file of the channel's section, dated by its newest video,
+// and returns the section path
+func (bc *blogConverter) writeChannelSection(channel youtube.Channel, date time.Time) (string, error) {
+	sectionPath, err := prepareFileName(bc.channelPathTmpl, map[string]any{
+		"Blog":    filename.Sanitize(bc.blog),
+		"Channel": filename.Sanitize(channel.Title),
+	})
+	if err != nil {
+		return "", fmt.Errorf("can't prepare channel file name: %w", err)
+	}
+	sfs, err := openBundle(bc.rfs, sectionPath)
+	if err != nil {
+		return "", fmt.Errorf("can't create Hugo channel directory: %w", err)
+	}
+	defer sfs.Close()
+
+	dst, err := sfs.Create("_index.md")
+	if err != nil {
+		return "", fmt.Errorf("can't create Hugo channel file: %w", err)
+	}
+	defer dst.Close()
+
+	pc := &postConverter{blogConverter: bc}
+	hp := HugoPost{
+		Blog:  bc.blog,
+		Title: channel.Title,
+		Date:  date,
+		Tags:  []string{"youtube"},
+		Params: map[string]string{
+			"channelId": channel.ID,
+		},
+	}
+	err = pc.Write(dst, hp)
+	if err != nil {
+		return "", fmt.Errorf("can't write Hugo channel file: %w", err)
+	}
+	return sectionPath, nil
+}
+
+func (bc *blogConverter) newChannelVideoConverter(ctx context.Context, channel youtube.Channel, sectionPath string, v *youtube.Video) error {
+	pc := &postConverter{
+		blogConverter: bc,
+		errors:        make(map[string]int),
+		resources:     make(map[string]*resource),
+	}
+	return pc.convertChannelVideo(ctx, channel, sectionPath, v)
+}
+
+// convertChannelVideo writes the video as a page bundle with its file, description and tags.
+// Private videos are written as drafts, and listed in the report.
+func (pc *postConverter) convertChannelVideo(ctx context.Context, channel youtube.Channel, sectionPath string, v *youtube.Video) error {
+	pc.hp = HugoPost{
+		Blog:  pc.blog,
+		Title: v.Title,
+		Date:  videoDate(v),
+		Draft: strings.EqualFold(v.Privacy, "private"),
+		Tags:  append([]string{"video"}, v.Tags...),
+		Params: map[string]string{
+			"channel":   channel.Title,
+			"youtubeId": v.ID,
+		},
+	}
+	if v.Privacy != "" {
+		pc.hp.Params["privacy"] = v.Privacy
+	}
+	if pc.hp.Title == "" {
+		pc.hp.Title = pc.hp.Date.Format("2006-01-02") + " - Untitled"
+	}
+	if pc.hp.Draft {
+		pc.report.add(privateVideosSection, channel.Title, fmt.Sprintf("%s (%s) written as a draft", pc.hp.Title, v.ID))
+	}
+
+	destPath := path.Join(sectionPath, pc.hp.Date.Format("2006-01-02")+" "+filename.Sanitize(pc.hp.Title))
+	var err error
+	pc.pfs, err = openBundle(pc.rfs, destPath)
+	if err != nil {
+		return fmt.Errorf("can't create Hugo video directory: %w", err)
+	}
+	defer pc.pfs.Close()
+
+	sb := strings.Builder{}
+	if v.Description != "" {
+		sb.WriteString(safeText(v.Description))
+		sb.WriteString("\n\n")
+	}
+	err = pc.renderVideo(ctx, &sb, video{
+		Resource: v.Resource,
+		YTId:     v.ID,
+		Source:   v.FileName,
+		Name:     filename.Sanitize(path.Base(v.FileName)),
+		Title:    v.Title,
+		Duration: v.Duration,
+		Poster:   v.Thumbnail,
+	})
+	if err != nil {
+		return err
+	}
+	pc.hp.content = sb.String()
+
+	dst, err := pc.pfs.Create("index.md")
+	if err != nil {
+		return fmt.Errorf("can't create Hugo video file: %w", err)
+	}
+	defer dst.Close()
+
+	err = pc.Write(dst, pc.hp)
+	if err != nil {
+		return fmt.Errorf("can't write Hugo video file: %w", err)
+	}
+	slog.Info("video converted", "blog", pc.hp.Blog, "date", pc.hp.Date, "title", pc.hp.Title)
+	return nil
+}
@@ -0,0 +1,112 @@
+package convert
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+	"bloggerout/internal/takeout/youtube"
+	"bloggerout/internal/worker"
+
+	"github.com/simulot/TakeoutLocalization/go/localization"
+)
+
+func TestConvertChannels(t *testing.T) {
+	vfs := fstest.MapFS{
+		"YouTube/channels/channel.csv": &fstest.MapFile{Data: []byte("Channel ID,Channel Title (Original)\nch1,Family\nch2,Work\n")},
+		"YouTube/video metadata/videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Video Description (Original),Channel ID,Video Title (Original),Privacy,Video Create Timestamp,Video Publish Timestamp
+id1,At the beach {{< fun >}},ch1,Holidays,Public,2019-07-14T10:00:00+00:00,2019-07-15T08:30:00+00:00
+id2,,ch1,Birthday,Private,2020-01-01T10:00:00+00:00,
+id3,,ch1,Lost,Public,2020-02-01T10:00:00+00:00,
+id4,,ch2,Meeting,Public,2020-03-01T10:00:00+00:00,
+`)},
+		"YouTube/video metadata/video tags.csv": &fstest.MapFile{Data: []byte("Video ID,Video Tag (Original)\nid1,beach\nid1,summer\n")},
+		"YouTube/videos/Holidays.mp4":           &fstest.MapFile{Data: []byte("video")},
+		"YouTube/videos/Birthday.mp4":           &fstest.MapFile{Data: []byte("video")},
+		"YouTube/videos/Meeting.mp4":            &fstest.MapFile{Data: []byte("video")},
+	}
+	rs := resources.New()
+	yt, err := youtube.New(vfs, rs, &localization.Products{}).Scan(context.Background(), "YouTube", "YouTube and YouTube Music")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+
+	bc := &blogConverter{
+		Convert: &Convert{
+			blogs:           []string{"Blog", "Other"},
+			channelsPattern: "Family",
+			channelPathTmpl: template.Must(template.New("channelPath").Parse("/content/videos/{{ .Channel }}/")),
+		},
+		blog:    "Blog",
+		data:    &takeout.Takeout{Resources: rs, YouTube: yt},
+		workers: worker.NewWorkerPool(2),
+		report:  newConversionReport(),
+		rfs:     rfs,
+	}
+	bc.workers.Start(context.Background())
+	bc.convertChannels(context.Background())
+	bc.workers.Stop()
+
+	read := func(name string) string {
+		t.Helper()
+		b, err := os.ReadFile(filepath.Join(rfs.Name(), name))
+		if err != nil {
+			t.Errorf("can't read %s: %v", name, err)
+		}
+		return string(b)
+	}
+
+	if section := read("content/videos/Family/_index.md"); !strings.Contains(section, "title: Family") || !strings.Contains(section, "2020-01-01") {
+		t.Errorf("unexpected section: %s", section)
+	}
+	page := read("content/videos/Family/2019-07-15 Holidays/index.md")
+	for _, want := range []string{"draft: false", "- beach", "- summer", "youtubeId: id1", "At the beach {&#123;< fun >}}", `src="Holidays.mp4"`} {
+		if !strings.Contains(page, want) {
+			t.Errorf("%q not found in %s", want, page)
+		}
+	}
+	if _, err := rfs.Stat("content/videos/Family/2019-07-15 Holidays/Holidays.mp4"); err != nil {
+		t.Errorf("video not copied: %v", err)
+	}
+	if page := read("content/videos/Family/2020-01-01 Birthday/index.md"); !strings.Contains(page, "draft: true") {
+		t.Errorf("private video not a draft: %s", page)
+	}
+	if entries := bc.report.sections[privateVideosSection]; len(entries) != 1 || entries[0].Message != "Birthday (id2) written as a draft" {
+		t.Errorf("unexpected report %v", entries)
+	}
+	// the channel pages are not uses of the videos: they stay orphans
+	if orphans := rs.Orphans(); len(orphans["YouTube/ch1"]) != 2 {
+		t.Errorf("unexpected orphans %v", orphans)
+	}
+	for _, name := range []string{"content/videos/Family/2020-02-01 Lost", "content/videos/Work"} {
+		if _, err := rfs.Stat(name); err == nil {
+			t.Errorf("%s not expected", name)
+		}
+	}
+
+	// the channel is exported with the first blog only
+	other, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	obc := *bc
+	obc.blog, obc.rfs, obc.workers = "Other", other, worker.NewWorkerPool(2)
+	obc.workers.Start(context.Background())
+	obc.convertChannels(context.Background())
+	obc.workers.Stop()
+	if _, err := other.Stat("content/videos"); err == nil {
+		t.Errorf("channel exported twice")
+	}
+}
@@ -0,0 +1,149 @@
+package convert
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JohannesKaufmann/dom"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// brushLanguages translates the SyntaxHighlighter brush aliases into the languages
+// known by Hugo's highlighter
+var brushLanguages = map[string]string{
+	"as3":           "actionscript",
+	"actionscript3": "actionscript",
+	"shell":         "bash",
+	"c#":            "csharp",
+	"c-sharp":       "csharp",
+	"c++":           "cpp",
+	"delphi":        "pascal",
+	"erl":           "erlang",
+	"golang":        "go",
+	"js":            "javascript",
+	"jscript":       "javascript",
+	"pl":            "perl",
+	"ps":            "powershell",
+	"py":            "python",
+	"rails":         "ruby",
+	"ror":           "ruby",
+	"rb":            "ruby",
+	"patch":         "diff",
+	"plain":         "text",
+	"vb":            "vbnet",
+	"xhtml":         "html",
+	"xslt":          "xml",
+}
+
+// codeLanguage returns the language given by the class of a code block:
+// SyntaxHighlighter "brush: go; toolbar: false", prettify "prettyprint lang-js" or "language-go"
+func codeLanguage(class string) string {
+	lang := ""
+	if _, brush, found := strings.Cut(class, "brush:"); found {
+		lang, _, _ = strings.Cut(brush, ";")
+		lang = strings.TrimSpace(lang)
+	} else {
+		for _, part := range strings.Fields(class) {
+			if l, found := strings.CutPrefix(part, "language-"); found {
+				lang = l
+				break
+			}
+			if l, found := strings.CutPrefix(part, "lang-"); found {
+				lang = l
+				break
+			}
+		}
+	}
+	lang = strings.ToLower(lang)
+	if l, ok := brushLanguages[lang]; ok {
+		return l
+	}
+	return lang
+}
+
+// Manage <pre> tags
+// The language of the SyntaxHighlighter and prettify code blocks is given to the commonmark plugin
+// that writes the fenced code block.
+func (pc *postConverter) preHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	class := dom.GetAttributeOr(node, "class", "")
+	if dom.GetAttributeOr(node, "name", "") == "code" {
+		// SyntaxHighlighter 1.5: <pre name="code" class="c-sharp">
+		class = "brush: " + class
+	}
+	lang := codeLanguage(class)
+	if lang == "" {
+		if code := dom.FindFirstNode(node, func(n *html.Node) bool { return dom.NodeName(n) == "code" }); code != nil {
+			lang = codeLanguage(dom.GetAttributeOr(code, "class", ""))
+		}
+	}
+	if lang != "" {
+		setAttribute(node, "class", "language-"+lang)
+	}
+	return converter.RenderTryNext
+}
+
+// Manage <script> tags
+// Gist embeds are rendered with the embed/gist shortcode, other scripts are dropped.
+func (pc *postConverter) scriptHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	if gist := gistShortcode(dom.GetAttributeOr(node, "src", "")); gist != "" {
+		w.WriteString("\n\n" + gist + "\n\n")
+	}
+	return converter.RenderSuccess
+}
+
+// codePreRenderer replaces the SyntaxHighlighter code held by <script> and <textarea> tags
+// with <pre> tags, before the whitespace of the text is collapsed:
+//
+//	<script type="syntaxhighlighter" class="brush: go"><![CDATA[ ... ]]></script>
+//	<textarea name="code" class="go"> ... </textarea>
+func codePreRenderer(_ converter.Context, doc *html.Node) {
+	for _, node := range dom.FindAllNodes(doc, func(n *html.Node) bool {
+		switch dom.NodeName(n) {
+		case "script":
+			return dom.GetAttributeOr(n, "type", "") == "syntaxhighlighter"
+		case "textarea":
+			return dom.GetAttributeOr(n, "name", "") == "code"
+		}
+		return false
+	}) {
+		class := dom.GetAttributeOr(node, "class", "")
+		if !strings.Contains(class, "brush:") {
+			class = "brush: " + class
+		}
+
+		code := strings.TrimSpace(dom.CollectText(node))
+		code = strings.TrimPrefix(code, "<![CDATA[")
+		code = strings.TrimSuffix(code, "]]>")
+		code = strings.Trim(code, "\r\n")
+
+		pre := &html.Node{Type: html.ElementNode, Data: "pre", DataAtom: atom.Pre}
+		if lang := codeLanguage(class); lang != "" {
+			pre.Attr = []html.Attribute{{Key: "class", Val: "language-" + lang}}
+		}
+		pre.AppendChild(&html.Node{Type: html.TextNode, Data: code})
+		node.Parent.InsertBefore(pre, node)
+		node.Parent.RemoveChild(node)
+	}
+}
+
+// gistShortcode returns the embed/gist shortcode for a gist embed script:
+// https://gist.github.com/user/id.js?file=name
+func gistShortcode(src string) string {
+	u, err := url.Parse(src)
+	if src == "" || err != nil || u.Hostname() != "gist.github.com" {
+		return ""
+	}
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) != 2 || path.Ext(parts[1]) != ".js" {
+		return ""
+	}
+	sc := "{{< embed/gist " + parts[0] + " " + strings.TrimSuffix(parts[1], ".js")
+	if file := u.Query().Get("file"); file != "" {
+		sc += " " + strconv.Quote(file)
+	}
+	return sc + " >}}"
+}
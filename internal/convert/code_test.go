@@ -0,0 +1,47 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+)
+
+func TestCodeBlocks(t *testing.T) {
+	testCases := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"brush", `<pre class="brush: go; toolbar: false">func main() {<br />}</pre>`, "```go\nfunc main() {\n}\n```"},
+		{"brush_alias", `<pre class="brush:js">var a = 1;</pre>`, "```javascript\nvar a = 1;\n```"},
+		{"brush_c", `<pre class="brush: c">int a;</pre>`, "```c\nint a;\n```"},
+		{"prettify", `<pre class="prettyprint lang-py linenums">print(1)</pre>`, "```python\nprint(1)\n```"},
+		{"code_class", `<pre><code class="language-sql">SELECT 1;</code></pre>`, "```sql\nSELECT 1;\n```"},
+		{"highlighter_1.5", `<pre name="code" class="c-sharp">int a;</pre>`, "```csharp\nint a;\n```"},
+		{"untagged", `<pre>plain text</pre>`, "```\nplain text\n```"},
+		{"script", "<script type=\"syntaxhighlighter\" class=\"brush: bash\"><![CDATA[\necho a < b\n\necho c\n]]></script>", "```bash\necho a < b\n\necho c\n```"},
+		{"textarea", "<textarea name=\"code\" class=\"xml\" cols=\"60\"><a>\n  <b/>\n</a></textarea>", "```xml\n<a>\n  <b/>\n</a>\n```"},
+		{"gist", `<script src="https://gist.github.com/jdoe/0123abcd.js?file=main.go"></script>`, `{{< embed/gist jdoe 0123abcd "main.go" >}}`},
+		{"gist_all_files", `<script src="https://gist.github.com/jdoe/0123abcd.js"></script>`, `{{< embed/gist jdoe 0123abcd >}}`},
+		{"other_script", `<p>text</p><script src="https://example.com/counter.js"></script>`, "text"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &postConverter{blogConverter: &blogConverter{report: newConversionReport()}}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("pre", converter.TagTypeBlock, pc.preHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("script", converter.TagTypeBlock, pc.scriptHandler, converter.PriorityEarly)
+			mdc.Register.PreRenderer(codePreRenderer, converter.PriorityEarly-10)
+
+			got, err := mdc.ConvertString(postBody(tc.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -5,28 +5,50 @@ import (
 	"errors"
 	"fmt"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 
+	"bloggerout/internal/downloader"
 	"bloggerout/internal/takeout"
 
 	"github.com/spf13/cobra"
 )
 
 type Convert struct {
-	data           *takeout.Takeout
-	blogs          []string
-	outPath        string
-	selectPattern  string
-	takeoutPath    []string
-	hugoPath       string
-	hugoPathTmpl   *template.Template // hugo path template
-	imagePath      string             // image path flag
-	imagePathTmpl  *template.Template // image path template
-	postPath       string             // post path flag
-	postPathTmpl   *template.Template // post template
-	reportPath     string             // report path flag
-	reportPathTmpl *template.Template // report template
+	data              *takeout.Takeout
+	blogs             []string
+	outPath           string
+	selectPattern     string
+	takeoutPath       []string
+	hugoPath          string
+	hugoPathTmpl      *template.Template // hugo path template
+	imagePath         string             // image path flag
+	imagePathTmpl     *template.Template // image path template
+	postPath          string             // post path flag
+	postPathTmpl      *template.Template // post template
+	reportPath        string             // report path flag
+	reportPathTmpl    *template.Template // report template
+	albumsPattern     string             // albums flag
+	albumPath         string             // album path flag
+	albumPathTmpl     *template.Template // album template
+	channelsPattern   string             // channels flag
+	channelPath       string             // channel path flag
+	channelPathTmpl   *template.Template // channel template
+	copyOrphans       bool               // orphans flag
+	resize            int                // maximum dimension of the images, 0 to keep them untouched
+	quality           int                // JPEG quality of the resized images
+	keepOriginal      bool               // keep the original image alongside the resized one
+	stripPrivate      bool               // remove GPS, serial numbers and owner from the published JPEG files and figures
+	summary           bool               // set the summary front matter with the text before the jump break
+	clickToLoad       bool               // embedded contents are loaded when the reader clicks on them
+	download          downloader.Options // retries, rate limits and user agent of the downloads
+	archiveFallback   bool               // download the lost images from the Internet Archive
+	linkCheck         bool               // check the external links once the posts are converted
+	linkWorkers       int                // simultaneous link checks
+	archiveDeadLinks  bool               // replace the dead links by their Internet Archive snapshot
+	attachments       bool               // download the linked documents and audio files into the page bundle
+	attachmentMaxSize int64              // size limit of an attachment in bytes, 0 for the download size limit only
 
 	// workers    *worker.WorkerPool
 	// downloader *downloader.Downloader
@@ -34,7 +56,10 @@ type Convert struct {
 }
 
 func ConverCommand() *cobra.Command {
-	c := &Convert{}
+	c := &Convert{download: downloader.DefaultOptions()}
+	if dir, err := os.UserCacheDir(); err == nil {
+		c.download.CacheDir = filepath.Join(dir, "bloggerout")
+	}
 
 	cmd := &cobra.Command{
 		Use:   "convert",
@@ -97,12 +122,6 @@ func ConverCommand() *cobra.Command {
 				fmt.Println("Missing the blog name pattern. Use --select * to export all blogs.")
 				os.Exit(1)
 			}
-			// c.imagePathTmpl, err = template.New("imagePath").Parse(c.imagePath)
-			// if err != nil {
-			// 	fmt.Printf("Error can't parse image path template: %v\n", err)
-			// 	os.Exit(1)
-			// }
-
 			c.postPathTmpl, err = template.New("postPath").Parse(c.postPath)
 			if err != nil {
 				fmt.Printf("Error can't parse post path template: %v\n", err)
@@ -113,6 +132,17 @@ func ConverCommand() *cobra.Command {
 				fmt.Printf("Error can't parse report path template: %v\n", err)
 				os.Exit(1)
 			}
+			c.albumPathTmpl, err = template.New("albumPath").Parse(c.albumPath)
+			if err != nil {
+				fmt.Printf("Error can't parse album path template: %v\n", err)
+				os.Exit(1)
+			}
+
+			c.channelPathTmpl, err = template.New("channelPath").Parse(c.channelPath)
+			if err != nil {
+				fmt.Printf("Error can't parse channel path template: %v\n", err)
+				os.Exit(1)
+			}
 
 			err = c.Convert(ctx)
 			if err != nil {
@@ -125,9 +155,36 @@ func ConverCommand() *cobra.Command {
 	cmd.Flags().StringVarP(&c.selectPattern, "select", "s", "", "Blog name or pattern to select specific blogs, '*' to select all blogs")
 	cmd.Flags().StringSliceVar(&c.takeoutPath, "takeout", nil, "Path to Takeout file, can be specified multiple times (required)")
 	cmd.Flags().StringVar(&c.hugoPath, "hugo", "", "Path to Hugo blogs directory (required)")
-	// cmd.Flags().StringVar(&c.imagePath, "image-path", "/static/images", "Path template for images inside hugo directory (default: /static/images)")
+	cmd.Flags().StringVar(&c.imagePath, "image-path", "", "Path template of a media store shared by all posts inside hugo directory, ex: /assets/media (default: images are stored in the post bundles)")
 	cmd.Flags().StringVar(&c.postPath, "post-path", "/content/posts/{{ .Title }}/", "Path template for posts inside hugo directory (default: /content/posts/{{ .Title }}/)")
 	cmd.Flags().StringVar(&c.reportPath, "report-path", "/content/reports", "Path template for posting import reports inside hugo directory (default: /content/report)")
+	cmd.Flags().StringVar(&c.albumsPattern, "albums", "", "Album name or pattern to export albums as galleries, '*' to export all albums")
+	cmd.Flags().StringVar(&c.albumPath, "album-path", "/content/albums/", "Path template for albums inside hugo directory (default: /content/albums/)")
+	cmd.Flags().StringVar(&c.channelsPattern, "channels", "", "YouTube channel name or pattern to export the channel's videos as a Hugo section, '*' to export all channels")
+	cmd.Flags().StringVar(&c.channelPath, "channel-path", "/content/videos/{{ .Channel }}/", "Path template for the YouTube channel sections inside hugo directory (default: /content/videos/{{ .Channel }}/)")
+	cmd.Flags().BoolVar(&c.copyOrphans, "orphans", false, "Copy the photos and videos never referenced by a post into an 'Unpublished photos' page per blog")
+	cmd.Flags().IntVar(&c.resize, "resize", 0, "Apply the EXIF orientation and reduce the JPEG, PNG and GIF images to this maximum width or height in pixels, 0 to copy the images untouched")
+	cmd.Flags().IntVar(&c.quality, "quality", 85, "JPEG quality of the resized images")
+	cmd.Flags().BoolVar(&c.keepOriginal, "keep-original", false, "Keep the original image alongside the resized one, and link it from the figure")
+	cmd.Flags().BoolVar(&c.stripPrivate, "strip-private", false, "Remove the GPS position, serial numbers and owner from the EXIF data of the published JPEG files, keeping orientation and capture date, and leave the Google Photos location out of the figures")
+	cmd.Flags().BoolVar(&c.summary, "summary", false, "Set the summary front matter of the posts with the text preceding Blogger's jump break")
+	cmd.Flags().BoolVar(&c.clickToLoad, "click-to-load", false, "Render the maps, videos and social media embeds as placeholders contacting the provider only when clicked")
+	cmd.Flags().IntVar(&c.download.Retries, "retries", c.download.Retries, "Number of retries of a download failing with a network error, a 429 or a 5xx status")
+	cmd.Flags().DurationVar(&c.download.Backoff, "retry-delay", c.download.Backoff, "Delay before the first retry of a download, doubled at each retry unless the server gives a Retry-After")
+	cmd.Flags().IntVar(&c.download.HostConcurrency, "host-concurrency", c.download.HostConcurrency, "Maximum number of simultaneous downloads from a same host, 0 for no limit")
+	cmd.Flags().Float64Var(&c.download.HostRate, "host-rate", c.download.HostRate, "Maximum number of requests per second to a same host, 0 for no limit")
+	cmd.Flags().StringVar(&c.download.UserAgent, "user-agent", c.download.UserAgent, "User-Agent header of the downloads")
+	cmd.Flags().StringVar(&c.download.CacheDir, "cache-dir", c.download.CacheDir, "Directory of the download cache, revalidated with the servers at each run, empty to disable the cache")
+	cmd.Flags().BoolVar(&c.download.Offline, "offline", false, "Use only the contents of the download cache, the others are reported as missing")
+	cmd.Flags().BoolVar(&c.archiveFallback, "archive-fallback", false, "Download the images that can't be downloaded anymore from their Internet Archive snapshot closest to the post date")
+	cmd.Flags().StringVar(&c.download.ArchiveURL, "archive-url", c.download.ArchiveURL, "Base URL of the Internet Archive availability API")
+	cmd.Flags().BoolVar(&c.linkCheck, "check-links", false, "Check the external links and iframes of the converted posts, and report the failing ones")
+	cmd.Flags().IntVar(&c.linkWorkers, "link-workers", 8, "Number of links checked simultaneously")
+	cmd.Flags().BoolVar(&c.archiveDeadLinks, "archive-dead-links", false, "Replace the dead links by their Internet Archive snapshot (with --check-links)")
+	cmd.Flags().Int64Var(&c.download.MaxSize, "max-download-size", c.download.MaxSize, "Size limit of a download in bytes, 0 for no limit")
+	cmd.Flags().StringSliceVar(&c.download.Placeholders, "placeholder", nil, "Placeholder image to reject when downloaded besides the known ones: its SHA-256 in hexadecimal, or its dimensions and format like \"400x300 png\", can be specified multiple times")
+	cmd.Flags().BoolVar(&c.attachments, "attachments", false, "Download the linked PDF, office documents and audio files into the page bundle, and link the local copy")
+	cmd.Flags().Int64Var(&c.attachmentMaxSize, "attachment-max-size", 20<<20, "Size limit of an attachment in bytes, 0 for no other limit than --max-download-size")
 	cmd.MarkFlagRequired("takeout")
 	cmd.MarkFlagRequired("hugo")
 
@@ -136,11 +193,26 @@ func ConverCommand() *cobra.Command {
 
 func (c *Convert) Convert(ctx context.Context) error {
 	var err error
+	bcs := []*blogConverter{}
 	for _, blog := range c.blogs {
-		err1 := newBlogConverter(ctx, c, c.data, blog)
+		bc, err1 := newBlogConverter(ctx, c, c.data, blog)
 		if err1 != nil {
 			err = errors.Join(err, err1)
 		}
+		if bc != nil {
+			bcs = append(bcs, bc)
+		}
+	}
+
+	// orphans are known once all blogs are converted
+	orphans := c.data.Resources.Orphans()
+	for _, bc := range bcs {
+		err = errors.Join(err, bc.reportOrphans(ctx, orphans))
+		bc.reportUnmatchedVideos()
+		if c.linkCheck {
+			bc.checkLinks(ctx)
+		}
+		err = errors.Join(err, bc.writeReport())
 	}
 	return err
 }
@@ -0,0 +1,193 @@
+package convert
+
+import (
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/JohannesKaufmann/dom"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// embeddedContent is a content of another site embedded in a post, rendered by the embed/<provider> shortcode
+type embeddedContent struct {
+	provider string            // shortcode name in _shortcodes/embed
+	params   map[string]string // shortcode parameters
+}
+
+// shortcode returns the shortcode of the embed.
+// With clickToLoad, the shortcode renders a placeholder, the site is contacted when the reader clicks on it.
+func (e embeddedContent) shortcode(clickToLoad bool) string {
+	sb := strings.Builder{}
+	sb.WriteString("{{< embed/" + e.provider)
+	for _, k := range []string{"id", "type", "url", "src"} {
+		if v, ok := e.params[k]; ok {
+			sb.WriteString(" " + k + "=" + strconv.Quote(v))
+		}
+	}
+	if clickToLoad {
+		sb.WriteString(` load="click"`)
+	}
+	sb.WriteString(" >}}")
+	return sb.String()
+}
+
+// parseEmbed recognizes the embed URLs of the supported providers:
+// iframe and player URLs, or the links to the content given by the blockquote forms.
+func parseEmbed(link string) (embeddedContent, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
+	if err != nil {
+		return embeddedContent{}, false
+	}
+	if u.Scheme == "" {
+		u.Scheme = "https" // protocol relative URL: //player.vimeo.com/video/123
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	last := parts[len(parts)-1]
+	q := u.Query()
+
+	switch {
+	case strings.HasPrefix(host, "maps.google.") ||
+		(strings.HasPrefix(host, "google.") && len(parts) > 0 && parts[0] == "maps"):
+		if q.Get("output") != "embed" && !strings.HasPrefix(u.Path, "/maps/embed") {
+			return embeddedContent{}, false
+		}
+		u.Scheme = "https"
+		return embeddedContent{"map", map[string]string{"src": u.String()}}, true
+
+	case host == "player.vimeo.com" && len(parts) == 2 && parts[0] == "video",
+		host == "vimeo.com" && len(parts) == 1 && isDigits(last):
+		return embeddedContent{"vimeo", map[string]string{"id": last}}, true
+	case host == "vimeo.com" && q.Get("clip_id") != "":
+		// flash player: http://vimeo.com/moogaloop.swf?clip_id=123
+		return embeddedContent{"vimeo", map[string]string{"id": q.Get("clip_id")}}, true
+
+	case host == "dailymotion.com" && len(parts) >= 2 && parts[len(parts)-2] == "video",
+		host == "dai.ly" && len(parts) == 1:
+		// https://www.dailymotion.com/embed/video/x7tgad0, /swf/video/x7tgad0, /video/x7tgad0_title
+		id, _, _ := strings.Cut(last, "_")
+		return embeddedContent{"dailymotion", map[string]string{"id": id}}, true
+
+	case host == "instagram.com" && len(parts) >= 2 && (parts[0] == "p" || parts[0] == "reel" || parts[0] == "tv"):
+		return embeddedContent{"instagram", map[string]string{"id": parts[1]}}, true
+
+	case (host == "twitter.com" || host == "x.com" || host == "mobile.twitter.com") && len(parts) >= 3 && parts[1] == "status":
+		return embeddedContent{"tweet", map[string]string{"id": parts[2]}}, true
+	case host == "platform.twitter.com" && q.Get("id") != "":
+		return embeddedContent{"tweet", map[string]string{"id": q.Get("id")}}, true
+
+	case host == "facebook.com" && u.Path == "/plugins/video.php" && q.Get("href") != "":
+		return embeddedContent{"facebook", map[string]string{"url": q.Get("href")}}, true
+	case host == "facebook.com" && strings.Contains(u.Path, "/videos/"):
+		return embeddedContent{"facebook", map[string]string{"url": u.String()}}, true
+
+	case host == "w.soundcloud.com" && q.Get("url") != "":
+		return embeddedContent{"soundcloud", map[string]string{"url": q.Get("url")}}, true
+
+	case host == "widget.deezer.com" && len(parts) >= 4 && parts[0] == "widget":
+		// https://widget.deezer.com/widget/dark/album/302127
+		return embeddedContent{"deezer", map[string]string{"type": parts[len(parts)-2], "id": last}}, true
+	case host == "deezer.com" && path.Base(u.Path) == "player" && q.Get("id") != "":
+		// https://www.deezer.com/plugins/player?type=tracks&id=3135556
+		return embeddedContent{"deezer", map[string]string{"type": strings.TrimSuffix(q.Get("type"), "s"), "id": q.Get("id")}}, true
+	}
+	return embeddedContent{}, false
+}
+
+func isDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// renderEmbed writes the shortcode of the embed URL, and reports false when the URL isn't recognized
+func (pc *postConverter) renderEmbed(w converter.Writer, link string) bool {
+	e, ok := parseEmbed(link)
+	if !ok {
+		return false
+	}
+	w.WriteString("\n\n" + e.shortcode(pc.clickToLoad) + "\n\n")
+	return true
+}
+
+// embedSource returns the URL of the content played by an <object> or <embed> tag
+func embedSource(node *html.Node) string {
+	if src := dom.GetAttributeOr(node, "src", dom.GetAttributeOr(node, "data", "")); src != "" {
+		return src
+	}
+	for _, n := range dom.FindAllNodes(node, func(n *html.Node) bool {
+		name := dom.NodeName(n)
+		return name == "param" || name == "embed"
+	}) {
+		if dom.NodeName(n) == "embed" {
+			if src := dom.GetAttributeOr(n, "src", ""); src != "" {
+				return src
+			}
+			continue
+		}
+		switch strings.ToLower(dom.GetAttributeOr(n, "name", "")) {
+		case "movie", "src":
+			return dom.GetAttributeOr(n, "value", "")
+		}
+	}
+	return ""
+}
+
+// Manage <embed> tags, the flash players of the embed providers
+func (pc *postConverter) embedHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	if upload, ok := bloggerVideo(node); ok {
+		return pc.renderBloggerVideo(ctx, w, upload)
+	}
+	src := embedSource(node)
+	if pc.renderYouTube(ctx, w, src) {
+		return converter.RenderSuccess
+	}
+	if pc.renderEmbed(w, src) {
+		return converter.RenderSuccess
+	}
+	pc.log(w, UNKNOWN_OBJECT, "can't handle embed: "+src, node)
+	return converter.RenderSuccess
+}
+
+// Manage <blockquote> tags
+// The embed scripts of Twitter, Instagram and Facebook replace a blockquote holding a link to the content.
+// The script itself is dropped by scriptHandler.
+func (pc *postConverter) blockquoteHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	var link string
+	switch {
+	case dom.HasClass(node, "twitter-tweet") || dom.HasClass(node, "twitter-video"):
+		// the link to the tweet is the last one, after the links of the tweet's text
+		for _, a := range dom.FindAllNodes(node, func(n *html.Node) bool { return dom.NodeName(n) == "a" }) {
+			link = dom.GetAttributeOr(a, "href", link)
+		}
+	case dom.HasClass(node, "instagram-media"):
+		link = dom.GetAttributeOr(node, "data-instgrm-permalink", "")
+	case dom.HasClass(node, "fb-xfbml-parse-ignore"):
+		if a := dom.FindFirstNode(node, func(n *html.Node) bool { return dom.NodeName(n) == "a" }); a != nil {
+			link = dom.GetAttributeOr(a, "href", "")
+		}
+	default:
+		return converter.RenderTryNext
+	}
+	if pc.renderEmbed(w, link) {
+		return converter.RenderSuccess
+	}
+	return converter.RenderTryNext
+}
+
+// Manage <div class="fb-video" data-href="..."> tags of the Facebook SDK
+func (pc *postConverter) divHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	if dom.HasClass(node, "fb-video") && pc.renderEmbed(w, dom.GetAttributeOr(node, "data-href", "")) {
+		return converter.RenderSuccess
+	}
+	return converter.RenderTryNext
+}
@@ -0,0 +1,81 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+)
+
+func TestParseEmbed(t *testing.T) {
+	testCases := []struct {
+		link string
+		want string // shortcode, empty when not recognized
+	}{
+		{"https://www.google.com/maps/embed?pb=!1m18!1m12", `{{< embed/map src="https://www.google.com/maps/embed?pb=!1m18!1m12" >}}`},
+		{"http://maps.google.fr/maps?f=q&hl=fr&ie=UTF8&ll=45.7,4.8&output=embed", `{{< embed/map src="https://maps.google.fr/maps?f=q&hl=fr&ie=UTF8&ll=45.7,4.8&output=embed" >}}`},
+		{"http://maps.google.fr/maps?ll=45.7,4.8", ""},
+		{"//player.vimeo.com/video/76979871?title=0", `{{< embed/vimeo id="76979871" >}}`},
+		{"http://vimeo.com/moogaloop.swf?clip_id=2910853&server=vimeo.com", `{{< embed/vimeo id="2910853" >}}`},
+		{"https://www.dailymotion.com/embed/video/x7tgad0", `{{< embed/dailymotion id="x7tgad0" >}}`},
+		{"http://www.dailymotion.com/swf/video/xb4k2a_le-titre", `{{< embed/dailymotion id="xb4k2a" >}}`},
+		{"https://www.instagram.com/p/B1abcDEF/?utm_source=ig_embed", `{{< embed/instagram id="B1abcDEF" >}}`},
+		{"https://twitter.com/jdoe/status/1234567890?ref_src=twsrc", `{{< embed/tweet id="1234567890" >}}`},
+		{"https://x.com/jdoe/status/42", `{{< embed/tweet id="42" >}}`},
+		{"https://www.facebook.com/plugins/video.php?href=https%3A%2F%2Fwww.facebook.com%2Fjdoe%2Fvideos%2F101%2F&show_text=0", `{{< embed/facebook url="https://www.facebook.com/jdoe/videos/101/" >}}`},
+		{"https://w.soundcloud.com/player/?url=https%3A//api.soundcloud.com/tracks/293&color=ff5500", `{{< embed/soundcloud url="https://api.soundcloud.com/tracks/293" >}}`},
+		{"https://widget.deezer.com/widget/dark/album/302127", `{{< embed/deezer id="302127" type="album" >}}`},
+		{"http://www.deezer.com/plugins/player?autoplay=false&type=tracks&id=3135556", `{{< embed/deezer id="3135556" type="track" >}}`},
+		{"https://example.com/widget", ""},
+	}
+	for _, tc := range testCases {
+		got := ""
+		if e, ok := parseEmbed(tc.link); ok {
+			got = e.shortcode(false)
+		}
+		if got != tc.want {
+			t.Errorf("parseEmbed(%q) = %s; want %s", tc.link, got, tc.want)
+		}
+	}
+
+	e, _ := parseEmbed("https://vimeo.com/123")
+	if got, want := e.shortcode(true), `{{< embed/vimeo id="123" load="click" >}}`; got != want {
+		t.Errorf("shortcode(true) = %s; want %s", got, want)
+	}
+}
+
+func TestEmbedHandlers(t *testing.T) {
+	testCases := []struct {
+		name string
+		html string
+		want string
+	}{
+		{"tweet", `<blockquote class="twitter-tweet"><p>Hello <a href="https://t.co/x">#go</a></p>&mdash; John (@jdoe) <a href="https://twitter.com/jdoe/status/123?ref_src=twsrc">May 1, 2015</a></blockquote><script async src="https://platform.twitter.com/widgets.js"></script>`, `{{< embed/tweet id="123" >}}`},
+		{"instagram", `<blockquote class="instagram-media" data-instgrm-permalink="https://www.instagram.com/p/B1abc/"><a href="https://www.instagram.com/p/B1abc/">View</a></blockquote>`, `{{< embed/instagram id="B1abc" >}}`},
+		{"facebook", `<div class="fb-video" data-href="https://www.facebook.com/jdoe/videos/101/"></div>`, `{{< embed/facebook url="https://www.facebook.com/jdoe/videos/101/" >}}`},
+		{"flash", `<object width="400"><param name="movie" value="http://vimeo.com/moogaloop.swf?clip_id=29"></param><embed src="http://vimeo.com/moogaloop.swf?clip_id=29"></embed></object>`, `{{< embed/vimeo id="29" >}}`},
+		{"iframe", `<iframe src="https://www.dailymotion.com/embed/video/x7t" width="480"></iframe>`, `{{< embed/dailymotion id="x7t" >}}`},
+		{"quote", `<blockquote>Just a quote</blockquote>`, `> Just a quote`},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &postConverter{blogConverter: &blogConverter{Convert: &Convert{}, report: newConversionReport()}, errors: map[string]int{}}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("iframe", converter.TagTypeBlock, pc.iFrameHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("object", converter.TagTypeBlock, pc.videoObjectHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("embed", converter.TagTypeBlock, pc.embedHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("blockquote", converter.TagTypeBlock, pc.blockquoteHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("div", converter.TagTypeBlock, pc.divHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("script", converter.TagTypeBlock, pc.scriptHandler, converter.PriorityEarly)
+
+			got, err := mdc.ConvertString(postBody(tc.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q; want %q", got, tc.want)
+			}
+		})
+	}
+}
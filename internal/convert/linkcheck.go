@@ -0,0 +1,136 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"maps"
+	"net/url"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bloggerout/internal/downloader"
+	"bloggerout/internal/worker"
+)
+
+const linkCheckSection = "Link check"
+
+// linkRef is a post referencing an external link
+type linkRef struct {
+	post string    // post title
+	path string    // post bundle path
+	date time.Time // post date, to get the closest archive snapshot
+}
+
+// linkSet collects the external links of the converted posts.
+// It is safe for concurrent use.
+type linkSet struct {
+	mu   sync.Mutex
+	refs map[string][]linkRef // by URL
+}
+
+func newLinkSet() *linkSet {
+	return &linkSet{refs: make(map[string][]linkRef)}
+}
+
+// collectLink records the external link of the post for the link check pass
+func (pc *postConverter) collectLink(link string) {
+	if pc.path == "" || !pc.linkCheck {
+		return
+	}
+	u, err := url.Parse(link)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return
+	}
+	pc.links.mu.Lock()
+	defer pc.links.mu.Unlock()
+	ref := linkRef{post: pc.hp.Title, path: pc.path, date: pc.hp.Date}
+	if !slices.Contains(pc.links.refs[link], ref) {
+		pc.links.refs[link] = append(pc.links.refs[link], ref)
+	}
+}
+
+// checkLinks checks the external links of the converted posts with a bounded pool,
+// reports the failing ones, and replaces the dead ones by their archive snapshot when requested.
+// It must run once the posts are written.
+func (bc *blogConverter) checkLinks(ctx context.Context) {
+	if bc.download.Offline {
+		slog.Warn("links aren't checked in offline mode", "blog", bc.blog)
+		return
+	}
+	pool := worker.NewWorkerPool(max(bc.linkWorkers, 1))
+	pool.Start(ctx)
+	var rewrite sync.Mutex // posts are rewritten one at a time
+	for _, link := range slices.Sorted(maps.Keys(bc.links.refs)) {
+		refs := bc.links.refs[link]
+		pool.Submit(func(ctx context.Context) {
+			c := bc.downloader.CheckLink(ctx, link)
+			if c.Status == downloader.LinkOK {
+				return
+			}
+			for _, ref := range refs {
+				bc.report.add(linkCheckSection, ref.post, c.String()+": "+link)
+			}
+			if !c.Dead() || !bc.archiveDeadLinks {
+				return
+			}
+			snapshot, err := bc.downloader.Snapshot(ctx, link, refs[0].date)
+			if err != nil {
+				slog.Warn("no archive for the dead link", "link", link, "error", err)
+				return
+			}
+			rewrite.Lock()
+			defer rewrite.Unlock()
+			for _, ref := range refs {
+				done, err := bc.rewriteLink(ref.path, link, snapshot)
+				switch {
+				case err != nil:
+					slog.Error("can't replace the dead link", "post", ref.post, "link", link, "error", err)
+				case done:
+					bc.report.add(linkCheckSection, ref.post, "replaced by "+snapshot+": "+link)
+				}
+			}
+		})
+	}
+	pool.Stop()
+}
+
+// rewriteLink replaces the link in the post's markdown links and shortcode parameters
+func (bc *blogConverter) rewriteLink(bundle string, link string, target string) (bool, error) {
+	name := path.Join(bundle, "index.md")
+	f, err := bc.rfs.Open(name)
+	if err != nil {
+		return false, err
+	}
+	b, err := io.ReadAll(f)
+	f.Close()
+	if err != nil {
+		return false, err
+	}
+	content := string(b)
+	// the whole link is matched, a longer link starting with the same URL is kept
+	r := strings.NewReplacer(
+		"]("+link+")", "]("+target+")",
+		"]("+link+` "`, "]("+target+` "`,
+		"](<"+link+">", "](<"+target+">",
+		`"`+link+`"`, `"`+target+`"`,
+	)
+	replaced := r.Replace(content)
+	if replaced == content {
+		return false, nil
+	}
+	f, err = bc.rfs.Create(name)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	_, err = io.WriteString(f, replaced)
+	if err != nil {
+		return false, fmt.Errorf("can't rewrite %s: %w", name, err)
+	}
+	return true, nil
+}
@@ -0,0 +1,101 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"bloggerout/internal/downloader"
+)
+
+func TestCheckLinks(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/moved":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/wayback/available":
+			if !strings.HasSuffix(r.URL.Query().Get("url"), "/gone") {
+				fmt.Fprint(w, `{"archived_snapshots": {}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"archived_snapshots": {"closest": {"available": true, "url": "%s/web/20100101000000/%s/gone", "status": "200"}}}`, srv.URL, srv.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	rfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+	err = rfs.Mkdir("post", 0o755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := fmt.Sprintf("[ok](%[1]s/ok) [moved](%[1]s/moved) [gone](%[1]s/gone) [lost](%[1]s/lost) [gone too](%[1]s/gone \"title\")\n[prefixed](%[1]s/goneaway) [query](%[1]s/gone?page=2)\n{{< iframe \"%[1]s/gone\" >}}\n", srv.URL)
+	err = os.WriteFile(rfs.Name()+"/post/index.md", []byte(page), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := downloader.DefaultOptions()
+	o.Retries = 0
+	o.ArchiveURL = srv.URL
+	bc := &blogConverter{
+		Convert:    &Convert{linkCheck: true, linkWorkers: 2, archiveDeadLinks: true},
+		downloader: downloader.NewDownloader(o),
+		report:     newConversionReport(),
+		links:      newLinkSet(),
+		rfs:        rfs,
+	}
+	pc := &postConverter{blogConverter: bc, hp: HugoPost{Title: "Post", Date: time.Date(2009, 12, 24, 0, 0, 0, 0, time.UTC)}, path: "post"}
+	for _, link := range []string{"/ok", "/moved", "/gone", "/lost", "/gone"} {
+		pc.collectLink(srv.URL + link)
+	}
+	pc.collectLink("mailto:someone@example.com")
+	if len(bc.links.refs) != 4 {
+		t.Errorf("unexpected links %v", bc.links.refs)
+	}
+
+	bc.checkLinks(context.Background())
+
+	messages := []string{}
+	for _, e := range bc.report.sections[linkCheckSection] {
+		messages = append(messages, e.Message)
+	}
+	report := strings.Join(messages, "\n")
+	for _, want := range []string{
+		"redirect (301) to " + srv.URL + "/ok: " + srv.URL + "/moved",
+		"not found (404): " + srv.URL + "/gone",
+		"not found (404): " + srv.URL + "/lost",
+		"replaced by " + srv.URL + "/web/20100101000000/" + srv.URL + "/gone: " + srv.URL + "/gone",
+	} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report doesn't contain %q:\n%s", want, report)
+		}
+	}
+	for _, m := range messages {
+		if strings.HasSuffix(m, ": "+srv.URL+"/ok") {
+			t.Errorf("working link reported: %s", m)
+		}
+	}
+
+	b, err := os.ReadFile(rfs.Name() + "/post/index.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := srv.URL + "/web/20100101000000/" + srv.URL + "/gone"
+	want := fmt.Sprintf("[ok](%[1]s/ok) [moved](%[1]s/moved) [gone](%[2]s) [lost](%[1]s/lost) [gone too](%[2]s \"title\")\n[prefixed](%[1]s/goneaway) [query](%[1]s/gone?page=2)\n{{< iframe \"%[2]s\" >}}\n", srv.URL, snapshot)
+	if string(b) != want {
+		t.Errorf("unexpected page:\n%s\nwant:\n%s", b, want)
+	}
+}
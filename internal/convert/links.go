@@ -0,0 +1,84 @@
+package convert
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/JohannesKaufmann/dom"
+	"golang.org/x/net/html"
+)
+
+const linkAttributesSection = "Link attributes"
+
+// prepareLink sets the href of the anchor node to the link of the Hugo post:
+//   - #anchor links stay in the page,
+//   - mailto: links without text show the address,
+//   - relative links are resolved against the original post URL, their target isn't part of the Hugo site,
+//   - links without text show the URL.
+//
+// The target and rel attributes can't be expressed in Markdown, they are reported when they matter.
+func (pc *postConverter) prepareLink(node *html.Node, u *url.URL) {
+	switch {
+	case u.Scheme == "" && u.Host == "" && u.Path == "":
+		// #anchor in the page, kept as is
+	case u.Scheme == "mailto":
+		if linkText(node) == "" {
+			setText(node, u.Opaque)
+		}
+	case u.Scheme == "" && pc.url != "":
+		if base, err := url.Parse(pc.url); err == nil {
+			u = base.ResolveReference(u)
+			setAttribute(node, "href", u.String())
+		}
+	}
+	if linkText(node) == "" && dom.GetAttributeOr(node, "title", "") == "" {
+		setText(node, u.String())
+	}
+
+	if attrs := mattering(node); attrs != "" {
+		pc.report.add(linkAttributesSection, pc.hp.Title, u.String()+": "+attrs)
+	}
+}
+
+// mattering returns the target and rel attributes that change the link behavior:
+// a target other than the current window, and the rel values telling search engines
+// not to endorse the link.
+func mattering(node *html.Node) string {
+	attrs := []string{}
+	switch target := dom.GetAttributeOr(node, "target", ""); target {
+	case "", "_self", "_top", "_parent":
+	default:
+		attrs = append(attrs, "target="+target)
+	}
+	for _, rel := range strings.Fields(strings.ToLower(dom.GetAttributeOr(node, "rel", ""))) {
+		switch rel {
+		case "nofollow", "sponsored", "ugc":
+			attrs = append(attrs, "rel="+rel)
+		}
+	}
+	return strings.Join(attrs, ", ")
+}
+
+// linkText returns the visible text of the link
+func linkText(node *html.Node) string {
+	return strings.TrimSpace(dom.CollectText(node))
+}
+
+// setText replaces the content of the node by the text
+func setText(node *html.Node, text string) {
+	for c := node.FirstChild; c != nil; c = node.FirstChild {
+		node.RemoveChild(c)
+	}
+	node.AppendChild(&html.Node{Type: html.TextNode, Data: text})
+}
+
+// setAttribute sets the value of the node's attribute
+func setAttribute(node *html.Node, key string, val string) {
+	for i, a := range node.Attr {
+		if a.Key == key {
+			node.Attr[i].Val = val
+			return
+		}
+	}
+	node.Attr = append(node.Attr, html.Attribute{Key: key, Val: val})
+}
@@ -0,0 +1,94 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+)
+
+func TestAnchorHandler(t *testing.T) {
+	testCases := []struct {
+		name   string
+		html   string
+		want   string
+		report bool
+	}{
+		{"text", `<a href="https://example.com/photos">click here for the photos</a>`, `[click here for the photos](https://example.com/photos)`, false},
+		{"markup", `<a href="https://example.com/"><b>bold</b> link</a>`, `[**bold** link](https://example.com/)`, false},
+		{"title", `<a href="https://example.com/" title="The site">site</a>`, `[site](https://example.com/ "The site")`, false},
+		{"no_text", `<a href="https://example.com/page"></a>`, `[https://example.com/page](https://example.com/page)`, false},
+		{"mailto", `<a href="mailto:john@example.com"></a>`, `[john@example.com](mailto:john@example.com)`, false},
+		{"fragment", `<a href="#recipe">the recipe</a>`, `[the recipe](#recipe)`, false},
+		{"relative", `<a href="/2010/05/other.html">other post</a>`, `[other post](https://blog.example.com/2010/05/other.html)`, false},
+		{"named_anchor", `<a name="recipe">Recipe</a>`, `{{< anchor "recipe" >}}Recipe`, false},
+		{"target", `<a href="https://example.com/" target="_blank">site</a>`, `[site](https://example.com/)`, true},
+		{"nofollow", `<a href="https://example.com/" rel="nofollow">site</a>`, `[site](https://example.com/)`, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &postConverter{
+				blogConverter: &blogConverter{report: newConversionReport()},
+				url:           "https://blog.example.com/2010/04/post.html",
+				hp:            HugoPost{Title: "Post"},
+			}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("a", converter.TagTypeInline, pc.anchorHandler, converter.PriorityEarly+10)
+
+			got, err := mdc.ConvertString(tc.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q; want %q", got, tc.want)
+			}
+			if reported := len(pc.report.sections[linkAttributesSection]) > 0; reported != tc.report {
+				t.Errorf("reported = %v; want %v", reported, tc.report)
+			}
+		})
+	}
+}
+
+func TestJumpBreak(t *testing.T) {
+	testCases := []struct {
+		name    string
+		html    string
+		summary string
+	}{
+		{"comment", `<p>Intro <b>text</b></p><!-- more --><p>Rest</p>`, "Intro **text**"},
+		{"anchor", `<p>Intro</p><a name='more'></a><p>Rest</p><a name="more"></a><p>End</p>`, "Intro"},
+		{"none", `<p>Intro</p><p>Rest</p>`, ""},
+		{"shortcodes", `<p><a name="top"></a>Intro</p><p>Rest</p><!--more--><p>End</p>`, "Intro\n\nRest"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &postConverter{blogConverter: &blogConverter{report: newConversionReport()}}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("a", converter.TagTypeInline, pc.anchorHandler, converter.PriorityEarly+10)
+
+			got, err := mdc.ConvertString(markJumpBreak(tc.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			if tc.summary != "" {
+				want = 1
+			}
+			if n := strings.Count(got, summaryDivider); n != want {
+				t.Errorf("%d dividers in %q; want %d", n, got, want)
+			}
+			if s := summaryOf(got); s != tc.summary {
+				t.Errorf("summary = %q; want %q", s, tc.summary)
+			}
+		})
+	}
+}
+
+func TestSummaryOf(t *testing.T) {
+	content := "{{< figure src=\"a.jpg\" >}}\n\nIntro {{< anchor \"x\" >}}text\n\n{{< table >}}\n<table><tr><td>{{< anchor \"y\" >}}</td></tr></table>\n{{< /table >}}\n\n{{< gallery >}}\n{{< figure src=\"b.jpg\" >}}\n{{< /gallery >}}\n\nEnd {&#123; kept\n\n" + summaryDivider + "\n\nRest"
+	if got, want := summaryOf(content), "Intro text\n\nEnd {&#123; kept"; got != want {
+		t.Errorf("summaryOf() = %q; want %q", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package convert
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"bloggerout/internal/takeout/resources"
+)
+
+// mediaExtensions gives the file extensions of the media types, the first one is used when renaming
+var mediaExtensions = map[string][]string{
+	"image/jpeg": {".jpg", ".jpeg", ".jpe"},
+	"image/png":  {".png"},
+	"image/gif":  {".gif"},
+	"image/webp": {".webp"},
+	"image/bmp":  {".bmp"},
+	"video/mp4":  {".mp4", ".m4v"},
+	"video/webm": {".webm"},
+}
+
+// sniffMedia returns the media type of the data, based on its first bytes
+func sniffMedia(head []byte) string {
+	mime, _, _ := strings.Cut(http.DetectContentType(head), ";")
+	return mime
+}
+
+// withMediaExtension returns the file name with an extension matching the media type.
+// A wrong media extension is replaced, other extensions are kept and the right one is appended.
+// The name is untouched when the media type is unknown.
+func withMediaExtension(name string, mime string) string {
+	exts, ok := mediaExtensions[mime]
+	if !ok {
+		return name
+	}
+	ext := path.Ext(name)
+	for _, e := range exts {
+		if strings.EqualFold(ext, e) {
+			return name
+		}
+	}
+	if resources.IsImage(name) || resources.IsVideo(name) {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name + exts[0]
+}
+
+// isBloggerImageHost checks if the host serves the images of Blogger posts,
+// with URLs often without file extension. The other Google hosts, like docs.googleusercontent.com,
+// serve any kind of file.
+func isBloggerImageHost(host string) bool {
+	host = strings.ToLower(host)
+	if host == "blogger.googleusercontent.com" || strings.HasSuffix(host, ".bp.blogspot.com") {
+		return true
+	}
+	for _, domain := range []string{".googleusercontent.com", ".ggpht.com"} {
+		if sub, ok := strings.CutSuffix(host, domain); ok && strings.HasPrefix(sub, "lh") && !strings.Contains(sub, ".") {
+			return true
+		}
+	}
+	return false
+}
+
+// isImageLink checks if the link points to an image: an image file extension in any case,
+// or an extension-less URL of the Blogger image servers
+func isImageLink(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+	if resources.IsImage(u.Path) {
+		return true
+	}
+	return isBloggerImageHost(u.Hostname()) && path.Ext(u.Path) == ""
+}
+
+// imageName returns the file name of the image at the URL.
+// Extension-less URLs, like "https://blogger.googleusercontent.com/img/a/AVvXsE...=s1600",
+// are named after the SHA1 of the link, the extension is added once the content is known.
+func imageName(u *url.URL) string {
+	name := strings.ReplaceAll(path.Base(u.Path), "+", " ")
+	if path.Ext(name) != "" {
+		return name
+	}
+	return fmt.Sprintf("%x", sha1.Sum([]byte(u.String())))
+}
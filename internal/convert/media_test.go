@@ -0,0 +1,241 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"bloggerout/internal/downloader"
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+)
+
+func TestWithMediaExtension(t *testing.T) {
+	jpeg := []byte("\xFF\xD8\xFF\xE0\x00\x10JFIF\x00")
+	png := []byte("\x89PNG\x0D\x0A\x1A\x0A")
+	testCases := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"IMG_0001.JPG", jpeg, "IMG_0001.JPG"},
+		{"photo.jpeg", jpeg, "photo.jpeg"},
+		{"photo.png", jpeg, "photo.jpg"},
+		{"s1600-h", jpeg, "s1600-h.jpg"},
+		{"image.php", png, "image.php.png"},
+		{"notes.txt", []byte("some text"), "notes.txt"},
+	}
+	for _, tc := range testCases {
+		if got := withMediaExtension(tc.name, sniffMedia(tc.data)); got != tc.want {
+			t.Errorf("withMediaExtension(%q) = %q; want %q", tc.name, got, tc.want)
+		}
+	}
+}
+
+func TestIsImageLink(t *testing.T) {
+	testCases := []struct {
+		link string
+		want bool
+	}{
+		{"https://example.com/photo.jpg", true},
+		{"https://example.com/photo.JPG", true},
+		{"http://example.com/anim.gif", true},
+		{"https://example.com/photo.webp", true},
+		{"https://blogger.googleusercontent.com/img/a/AVvXsEh5=s1600", true},
+		{"http://1.bp.blogspot.com/_abc/TK2/AAA/s1600/photo", true},
+		{"https://lh3.googleusercontent.com/-abc/TK2/AAA/s1600/photo", true},
+		{"http://lh5.ggpht.com/_abc/TUw/AAA/s1600/photo", true},
+		{"https://example.com/page", false},
+		{"https://blogger.googleusercontent.com/page.html", false},
+		{"https://doc-0s-4c-docs.googleusercontent.com/docs/securesc/abc/def/1500000000000/123/456/7", false},
+		{"https://drive.usercontent.google.com/download?id=abc", false},
+		{"mailto:john@example.com", false},
+	}
+	for _, tc := range testCases {
+		u, _ := url.Parse(tc.link)
+		if got := isImageLink(u); got != tc.want {
+			t.Errorf("isImageLink(%q) = %v; want %v", tc.link, got, tc.want)
+		}
+	}
+}
+
+func TestImageName(t *testing.T) {
+	u, _ := url.Parse("https://example.com/a/my+photo.JPG")
+	if got := imageName(u); got != "my photo.JPG" {
+		t.Errorf("imageName() = %q; want %q", got, "my photo.JPG")
+	}
+
+	u1, _ := url.Parse("https://blogger.googleusercontent.com/img/a/AVvXsEh5=s1600")
+	u2, _ := url.Parse("https://blogger.googleusercontent.com/img/a/AVvXsEh6=s1600")
+	n1, n2 := imageName(u1), imageName(u2)
+	if n1 == n2 || path.Ext(n1) != "" {
+		t.Errorf("imageName() of extension-less URLs = %q, %q; want distinct names without extension", n1, n2)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	testCases := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0:00"},
+		{83 * time.Second, "1:23"},
+		{59*time.Minute + 59500*time.Millisecond, "1:00:00"},
+		{2*time.Hour + 3*time.Minute + 4*time.Second, "2:03:04"},
+	}
+	for _, tc := range testCases {
+		if got := formatDuration(tc.d); got != tc.want {
+			t.Errorf("formatDuration(%v) = %q; want %q", tc.d, got, tc.want)
+		}
+	}
+}
+
+func TestFigureLocation(t *testing.T) {
+	gif := []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+	vfs := fstest.MapFS{"Album/beach.gif": &fstest.MapFile{Data: gif}}
+	entries, err := fs.ReadDir(vfs, "Album")
+	if err != nil {
+		t.Fatal(err)
+	}
+	taken := time.Date(2015, 7, 14, 10, 0, 0, 0, time.UTC)
+	rs := resources.New()
+	r := rs.Add(vfs, "Album", "Album", entries[0], &resources.ResourceMetadata{
+		Filename:       "beach.gif",
+		PhotoTakenTime: taken,
+		GeoData:        resources.GeoData{Latitude: 48.85, Longitude: 2.35},
+		People:         []string{"Alice"},
+	})
+
+	testCases := []struct {
+		stripPrivate bool
+		location     bool
+	}{
+		{false, true},
+		{true, false},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.stripPrivate), func(t *testing.T) {
+			pfs, err := os.OpenRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pfs.Close()
+			pc := &postConverter{
+				blogConverter: &blogConverter{Convert: &Convert{stripPrivate: tc.stripPrivate}, data: &takeout.Takeout{Resources: rs}, report: newConversionReport()},
+				errors:        map[string]int{},
+				resources:     map[string]*resource{},
+				pfs:           pfs,
+			}
+			var buf bytes.Buffer
+			err = pc.renderTakeoutImage(context.Background(), &buf, r, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := buf.String()
+			if location := strings.Contains(got, "lat=") || strings.Contains(got, "lon="); location != tc.location {
+				t.Errorf("location written: %v; want %v: %s", location, tc.location, got)
+			}
+			if !strings.Contains(got, `date="2015-07-14T10:00:00Z"`) {
+				t.Errorf("capture date missing: %s", got)
+			}
+			if strings.Contains(got, "Alice") {
+				t.Errorf("people names published: %s", got)
+			}
+		})
+	}
+}
+
+func TestImageDownloadedOnce(t *testing.T) {
+	gif := []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write(gif)
+	}))
+	defer srv.Close()
+
+	pfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pfs.Close()
+	o := downloader.DefaultOptions()
+	o.Retries = 0
+	bc := &blogConverter{Convert: &Convert{}, data: &takeout.Takeout{Resources: resources.New()}, downloader: downloader.NewDownloader(o), report: newConversionReport()}
+
+	// two runs of the conversion of a post showing the image twice
+	for run := range 2 {
+		pc := &postConverter{blogConverter: bc, errors: map[string]int{}, resources: map[string]*resource{}, pfs: pfs}
+		var buf bytes.Buffer
+		for range 2 {
+			err = pc.renderImage(context.Background(), &buf, srv.URL+"/photo.png", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		if n := strings.Count(buf.String(), `src="photo.gif"`); n != 1 {
+			t.Errorf("run %d: %d figures of photo.gif: %s", run, n, buf.String())
+		}
+	}
+	if requests != 1 {
+		t.Errorf("%d downloads; want 1", requests)
+	}
+	if _, err := pfs.Stat("photo.png"); err == nil {
+		t.Errorf("image saved under its original name")
+	}
+}
+
+func TestFiguresSameName(t *testing.T) {
+	holidays := []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+	party := []byte("GIF89a\x03\x00\x03\x00\x00\x00\x00;")
+	vfs := fstest.MapFS{
+		"Holidays/IMG_0001.gif": &fstest.MapFile{Data: holidays},
+		"Party/IMG_0001.gif":    &fstest.MapFile{Data: party},
+	}
+	rs := resources.New()
+	for _, container := range []string{"Holidays", "Party"} {
+		entries, err := fs.ReadDir(vfs, container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Add(vfs, "Google Photos/"+container, container, entries[0], nil)
+	}
+	pfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pfs.Close()
+	pc := &postConverter{
+		blogConverter: &blogConverter{Convert: &Convert{}, data: &takeout.Takeout{Resources: rs}, report: newConversionReport()},
+		errors:        map[string]int{},
+		resources:     map[string]*resource{},
+		pfs:           pfs,
+	}
+	var buf bytes.Buffer
+	for _, container := range []string{"Holidays", "Party"} {
+		err := pc.renderTakeoutImage(context.Background(), &buf, rs.SearchInContainer("Google Photos/"+container, "IMG_0001.gif"), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// each figure shows the photo of its own album
+	for name, want := range map[string][]byte{"IMG_0001.gif": holidays, "Party IMG_0001.gif": party} {
+		if !strings.Contains(buf.String(), `src="`+name+`"`) {
+			t.Errorf("%s not rendered: %s", name, buf.String())
+		}
+		if b, err := os.ReadFile(pfs.Name() + "/" + name); err != nil || !bytes.Equal(b, want) {
+			t.Errorf("unexpected content of %s: %q, %v", name, b, err)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package convert
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"maps"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	"bloggerout/internal/filename"
+	"bloggerout/internal/takeout/resources"
+)
+
+const orphansSection = "Orphan media"
+
+// reportOrphans reports the photos and videos never referenced by a converted post,
+// and copies them into an "Unpublished photos" page bundle when requested.
+// It must run after the conversion of all blogs.
+func (bc *blogConverter) reportOrphans(ctx context.Context, orphans map[string][]*resources.Resource) error {
+	owned := []*resources.Resource{}
+	for _, container := range slices.Sorted(maps.Keys(orphans)) {
+		if !bc.ownsContainer(container) {
+			continue
+		}
+		for _, r := range orphans[container] {
+			bc.report.add(orphansSection, container, r.Name()+" ("+r.CaptureTime().Format("2006-01-02")+")")
+			owned = append(owned, r)
+		}
+	}
+	if !bc.copyOrphans || len(owned) == 0 {
+		return nil
+	}
+
+	pc := &postConverter{
+		blogConverter: bc,
+		errors:        make(map[string]int),
+		resources:     make(map[string]*resource),
+	}
+	return pc.convertOrphans(ctx, owned)
+}
+
+// ownsContainer tells if the container's media belong to the blog:
+// all of them when a single blog is converted, otherwise the blog's own containers.
+func (bc *blogConverter) ownsContainer(container string) bool {
+	return len(bc.blogs) <= 1 || bc.isBlogContainer(container)
+}
+
+// isBlogContainer tells if the container holds the media uploaded to the blog
+func (bc *blogConverter) isBlogContainer(container string) bool {
+	return isBlogContainer(bc.data.Resources, bc.blog, container)
+}
+
+// isBlogContainer tells if the container holds the media uploaded to the blog:
+// the blog's folder, or an album named after the blog.
+func isBlogContainer(rs *resources.Resources, blog string, container string) bool {
+	if strings.EqualFold(resources.ContainerFolder(container), blog) {
+		return true
+	}
+	a := rs.SearchAlbum(container)
+	return a != nil && strings.EqualFold(a.Title, blog)
+}
+
+// blogContainers returns the blog's own containers
+func (bc *blogConverter) blogContainers() []string {
+	l := []string{}
+	for _, container := range bc.data.Resources.Containers() {
+		if bc.isBlogContainer(container) {
+			l = append(l, container)
+		}
+	}
+	return l
+}
+
+// convertOrphans writes the orphan media in a page bundle, grouped by container
+func (pc *postConverter) convertOrphans(ctx context.Context, orphans []*resources.Resource) error {
+	pc.hp = HugoPost{
+		Blog:  pc.blog,
+		Title: "Unpublished photos",
+		Date:  time.Now(),
+		Tags:  []string{"album"},
+	}
+
+	destPath, err := prepareFileName(pc.albumPathTmpl, map[string]any{
+		"Blog": filename.Sanitize(pc.hp.Blog),
+		"Date": pc.hp.Date,
+	})
+	if err != nil {
+		return fmt.Errorf("can't prepare unpublished photos file name: %w", err)
+	}
+	pc.pfs, err = openBundle(pc.rfs, path.Join(destPath, "Unpublished photos"))
+	if err != nil {
+		return fmt.Errorf("can't create unpublished photos directory: %w", err)
+	}
+
+	sb := strings.Builder{}
+	container := ""
+	names := map[string]bool{} // file names in the bundle
+	for _, r := range orphans {
+		if r.Container() != container {
+			if container != "" {
+				sb.WriteString("{{< /gallery >}}\n\n")
+			}
+			container = r.Container()
+			title := resources.ContainerFolder(container)
+			if a := pc.data.Resources.SearchAlbum(container); a != nil {
+				title = a.Title
+			}
+			fmt.Fprintf(&sb, "## %s\n\n{{< gallery >}}\n", safeText(title))
+		}
+		// files of different containers may have the same name
+		name := uniqueMediaName(filename.Sanitize(r.Name()), r, func(name string) bool { return names[name] })
+		names[name] = true
+		if resources.IsVideo(r.Name()) {
+			_ = pc.renderVideo(ctx, &sb, video{
+				Resource: r,
+				Source:   r.Name(),
+				Name:     name,
+				Caption:  r.Metadata().Description,
+			})
+			continue
+		}
+		_ = pc.renderFigure(ctx, &sb, &resource{Source: r.Name(), Name: name, Resource: r})
+	}
+	sb.WriteString("{{< /gallery >}}\n")
+	pc.hp.content = sb.String()
+
+	dst, err := pc.pfs.Create("index.md")
+	if err != nil {
+		return fmt.Errorf("can't create unpublished photos file: %w", err)
+	}
+	defer dst.Close()
+	err = pc.Write(dst, pc.hp)
+	if err != nil {
+		return fmt.Errorf("can't write unpublished photos file: %w", err)
+	}
+	slog.Info("unpublished photos exported", "blog", pc.blog, "count", len(orphans))
+	return nil
+}
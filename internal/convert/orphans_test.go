@@ -0,0 +1,91 @@
+package convert
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"text/template"
+	"time"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+)
+
+func TestOrphans(t *testing.T) {
+	gif := []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+	vfs := fstest.MapFS{
+		"Holidays/IMG_0001.gif": &fstest.MapFile{Data: gif},
+		"Party/IMG_0001.gif":    &fstest.MapFile{Data: gif},
+		"Party/IMG_0002.gif":    &fstest.MapFile{Data: gif},
+	}
+	rs := resources.New()
+	date := time.Date(2010, 6, 1, 0, 0, 0, 0, time.UTC)
+	for _, container := range []string{"Holidays", "Party"} {
+		entries, err := fs.ReadDir(vfs, container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			rs.Add(vfs, container, container, e, &resources.ResourceMetadata{Filename: e.Name(), CreationTimestamp: date})
+		}
+	}
+	rs.AddAlbum(&resources.Album{Title: "Holidays {{< x >}}", Container: "Holidays"})
+
+	rfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+	bc := &blogConverter{
+		Convert: &Convert{
+			blogs:         []string{"Blog"},
+			copyOrphans:   true,
+			albumPathTmpl: template.Must(template.New("albumPath").Parse("/content/albums/")),
+		},
+		data:   &takeout.Takeout{Resources: rs},
+		blog:   "Blog",
+		report: newConversionReport(),
+		rfs:    rfs,
+	}
+	pfs, err := rfs.OpenRoot(".")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pfs.Close()
+	pc := &postConverter{blogConverter: bc, errors: map[string]int{}, resources: map[string]*resource{}, pfs: pfs, countUses: true}
+	var sb strings.Builder
+	_ = pc.renderTakeoutImage(context.Background(), &sb, rs.SearchContainer("Party")[1], "")
+
+	// the album page doesn't reference its photos
+	err = bc.newAlbumConverter(context.Background(), rs.SearchAlbum("Holidays"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	orphans := rs.Orphans()
+	if len(orphans["Holidays"]) != 1 || len(orphans["Party"]) != 1 || orphans["Party"][0].Name() != "IMG_0001.gif" {
+		t.Fatalf("unexpected orphans %v", orphans)
+	}
+
+	err = bc.reportOrphans(context.Background(), orphans)
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, err := os.ReadFile(rfs.Name() + "/content/albums/Unpublished photos/index.md")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(page), "## Holidays {&#123;< x >}}\n") {
+		t.Errorf("album title not escaped:\n%s", page)
+	}
+	for _, name := range []string{"IMG_0001.gif", "Party IMG_0001.gif"} {
+		if !strings.Contains(string(page), `src="`+name+`"`) {
+			t.Errorf("%s not rendered:\n%s", name, page)
+		}
+		if _, err := os.Stat(rfs.Name() + "/content/albums/Unpublished photos/" + name); err != nil {
+			t.Errorf("%s not copied: %v", name, err)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package convert
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"bloggerout/internal/takeout/resources"
+
+	"github.com/JohannesKaufmann/dom"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// picasaDateTolerance is the maximum delay between the album's last photo and the post
+// to consider the album as the one embedded in the post
+const picasaDateTolerance = 15 * 24 * time.Hour
+
+const picasaAlbumsSection = "Picasa albums matched by date"
+
+// renderPicasaAlbum replaces a Picasa Web Album embedded in a post by a gallery
+// built with the album found in the takeout.
+//
+// The album is searched by its name in the URL (https://picasaweb.google.com/user/AlbumName),
+// by the caption of the embedding table, and finally by the post date.
+// The albums found by their date only are listed in the report.
+func (pc *postConverter) renderPicasaAlbum(ctx converter.Context, w converter.Writer, node *html.Node, u *url.URL) converter.RenderStatus {
+	album, guessed := pc.searchPicasaAlbum(u, picasaCaption(node))
+	if album == nil {
+		pc.log(w, CONTENT_LOST, fmt.Sprintf("The Picasa web albums service has been dismissed by Google: (%s)", u.String()))
+		return converter.RenderSuccess
+	}
+	if guessed {
+		pc.report.add(picasaAlbumsSection, pc.hp.Title, album.Title+" ("+album.Container+"): "+u.String())
+	}
+
+	slog.Info("Picasa album replaced by a gallery", "post", pc.hp.Title, "album", album.Title, "link", u.String())
+	pc.renderGallery(ctx, w, album)
+	return converter.RenderSuccess
+}
+
+// searchPicasaAlbum returns the album of the URL or of the caption, else the album closest to the post's date.
+// The guess by date never picks the photos uploaded to the blog.
+func (pc *postConverter) searchPicasaAlbum(u *url.URL, caption string) (album *resources.Album, guessed bool) {
+	rs := pc.data.Resources
+	if name := picasaAlbumName(u); name != "" {
+		if a := rs.SearchAlbumByName(name); a != nil {
+			return a, false
+		}
+	}
+	if caption != "" {
+		if a := rs.SearchAlbumByName(caption); a != nil {
+			return a, false
+		}
+	}
+	album = rs.SearchAlbumByDate(pc.hp.Date, picasaDateTolerance, func(a *resources.Album) bool {
+		return pc.isBlogContainer(a.Container)
+	})
+	return album, album != nil
+}
+
+// picasaCaption returns the album name from the caption of the embedding table: "From Album Name"
+func picasaCaption(node *html.Node) string {
+	text := strings.TrimSpace(html.UnescapeString(dom.CollectText(node)))
+	return strings.TrimSpace(strings.TrimPrefix(text, "From "))
+}
+
+// picasaAlbumName extracts the album name from the Picasa URL
+// https://picasaweb.google.com/user.name/AlbumName?feat=embedwebsite
+func picasaAlbumName(u *url.URL) string {
+	parts := strings.Split(strings.Trim(u.Path, "/"), "/")
+	if len(parts) < 2 {
+		return ""
+	}
+	switch parts[0] {
+	case "lh", "data", "home", "m":
+		return ""
+	}
+	name, _ := url.PathUnescape(parts[1])
+	return name
+}
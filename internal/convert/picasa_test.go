@@ -0,0 +1,50 @@
+package convert
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+
+	"golang.org/x/net/html"
+)
+
+func TestSearchPicasaAlbum(t *testing.T) {
+	date := time.Date(2009, 7, 14, 12, 0, 0, 0, time.UTC)
+	rs := resources.New()
+	rs.AddAlbum(&resources.Album{Title: "Blog", Container: "Blog", End: date})
+	rs.AddAlbum(&resources.Album{Title: "Été à la mer", Container: "Ete a la mer", End: date.AddDate(0, 0, -5)})
+	rs.AddAlbum(&resources.Album{Title: "Noël", Container: "Noel", End: date.AddDate(0, -6, 0)})
+
+	testCases := []struct {
+		name    string
+		link    string
+		caption string
+		want    string // container of the album
+		guessed bool
+	}{
+		{"url", "https://picasaweb.google.com/jean.dupont/Noel?feat=embedwebsite", "", "Noel", false},
+		{"caption", "https://picasaweb.google.com/lh/photo/abc?feat=embedwebsite", `From <a href="https://picasaweb.google.com/jean.dupont/x">Noël</a>`, "Noel", false},
+		{"date", "https://picasaweb.google.com/lh/photo/abc?feat=embedwebsite", "From Album", "Ete a la mer", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &postConverter{
+				blogConverter: &blogConverter{blog: "Blog", data: &takeout.Takeout{Resources: rs}},
+				hp:            HugoPost{Date: date},
+			}
+			node, err := html.Parse(strings.NewReader("<table><tr><td>" + tc.caption + "</td></tr></table>"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			u, _ := url.Parse(tc.link)
+			a, guessed := pc.searchPicasaAlbum(u, picasaCaption(node))
+			if a == nil || a.Container != tc.want || guessed != tc.guessed {
+				t.Errorf("searchPicasaAlbum() = %+v, %v; want %s, %v", a, guessed, tc.want, tc.guessed)
+			}
+		})
+	}
+}
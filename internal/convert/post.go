@@ -8,9 +8,11 @@ import (
 	"net/url"
 	"os"
 	"path"
+	"strconv"
 	"strings"
 	"time"
 
+	"bloggerout/internal/downloader"
 	"bloggerout/internal/filename"
 	"bloggerout/internal/takeout/blogger"
 
@@ -18,6 +20,7 @@ import (
 	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
 	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
 	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
 	"golang.org/x/net/html"
 	"gopkg.in/yaml.v3"
 )
@@ -36,6 +39,9 @@ type postConverter struct {
 	path       string   // Path to the post directory
 	pfs        *os.Root // post's file root
 	mdc        *converter.Converter
+	jumpBreak  bool                             // the summary divider has been written
+	countUses  bool                             // the rendered media are referenced by a post, they aren't orphans
+	attached   map[string]downloader.Attachment // downloaded attachments, by URL
 }
 
 func (bc *blogConverter) newPostConverter(ctx context.Context, post blogger.Post) error {
@@ -44,6 +50,7 @@ func (bc *blogConverter) newPostConverter(ctx context.Context, post blogger.Post
 		post:          post,
 		errors:        make(map[string]int),
 		resources:     make(map[string]*resource),
+		countUses:     true,
 	}
 	return pc.convertPost(ctx, post)
 }
@@ -55,6 +62,7 @@ type HugoPost struct {
 	Draft   bool
 	Tags    []string
 	Params  map[string]string
+	Summary string `yaml:",omitempty"`
 	content string
 }
 
@@ -87,26 +95,17 @@ func (pc *postConverter) convertPost(ctx context.Context, p blogger.Post) error
 	// Post path
 	destPath = path.Join(destPath, pc.hp.Date.Format("2006-01-02")+" "+filename.Sanitize(pc.hp.Title))
 
-	_, err = pc.rfs.Stat(destPath)
-	if err != nil {
-		if !os.IsNotExist(err) {
-			return fmt.Errorf("can't check if Hugo post file exists: %w", err)
-		}
-		err = mkDirAll(pc.rfs, destPath)
-		if err != nil {
-			return fmt.Errorf("can't create Hugo post directory: %w", err)
-		}
-	}
-
-	pc.pfs, err = pc.rfs.OpenRoot(destPath)
+	pc.pfs, err = openBundle(pc.rfs, destPath)
 	if err != nil {
 		return fmt.Errorf("can't create Hugo post directory: %w", err)
 	}
+	pc.path = destPath
 
 	mdc := converter.NewConverter(
 		converter.WithPlugins(
 			base.NewBasePlugin(),
 			commonmark.NewCommonmarkPlugin(),
+			table.NewTablePlugin(table.WithHeaderPromotion(true)),
 		),
 	)
 
@@ -116,15 +115,24 @@ func (pc *postConverter) convertPost(ctx context.Context, p blogger.Post) error
 	mdc.Register.RendererFor("img", converter.TagTypeInline, pc.imageHandler, converter.PriorityEarly)
 	mdc.Register.RendererFor("iframe", converter.TagTypeBlock, pc.iFrameHandler, converter.PriorityEarly)
 	mdc.Register.RendererFor("object", converter.TagTypeBlock, pc.videoObjectHandler, converter.PriorityEarly)
+	mdc.Register.RendererFor("embed", converter.TagTypeBlock, pc.embedHandler, converter.PriorityEarly)
+	mdc.Register.RendererFor("blockquote", converter.TagTypeBlock, pc.blockquoteHandler, converter.PriorityEarly)
+	mdc.Register.RendererFor("div", converter.TagTypeBlock, pc.divHandler, converter.PriorityEarly)
+	mdc.Register.RendererFor("pre", converter.TagTypeBlock, pc.preHandler, converter.PriorityEarly)
+	mdc.Register.RendererFor("script", converter.TagTypeBlock, pc.scriptHandler, converter.PriorityEarly)
+	mdc.Register.PreRenderer(codePreRenderer, converter.PriorityEarly-10) // before the base plugin removes the textarea tags
 
 	// capture tags not handled by the other handlers
 	mdc.Register.RendererFor("a", converter.TagTypeInline, pc.anchorHandler, converter.PriorityEarly+10)
 
 	// let's the magic happening
-	pc.hp.content, err = mdc.ConvertString(p.Content, converter.WithContext(ctx))
+	pc.hp.content, err = mdc.ConvertString(postBody(markJumpBreak(p.Content)), converter.WithContext(ctx))
 	if err != nil {
 		return err
 	}
+	if pc.summary {
+		pc.hp.Summary = summaryOf(pc.hp.content)
+	}
 
 	for _, c := range p.Comments {
 		text, err := mdc.ConvertString(c.Text, converter.WithContext(ctx))
@@ -147,6 +155,12 @@ func (pc *postConverter) convertPost(ctx context.Context, p blogger.Post) error
 	return nil
 }
 
+// postBody wraps the post content in a body element, so the scripts starting a post,
+// like gist embeds, aren't moved into the head of the parsed document and dropped
+func postBody(content string) string {
+	return "<body>" + content
+}
+
 func (pc *postConverter) log(w io.Writer, level, message string, node ...*html.Node) {
 	n := pc.errors[level]
 	pc.errors[level] = n + 1
@@ -259,10 +273,24 @@ func (pc *postConverter) imageHandler(ctx converter.Context, w converter.Writer,
 }
 
 // Manage <a> tags
-// When the href url is a picture, we convert it to an image, and ignore the inner HTML
-
+// When the href url is a picture, we convert it to an image, and ignore the inner HTML.
+// Other links are rendered by the commonmark plugin, with their inner content and title,
+// once the href is prepared by prepareLink.
 func (pc *postConverter) anchorHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
-	href := dom.GetAttributeOr(node, "href", "")
+	href := strings.TrimSpace(dom.GetAttributeOr(node, "href", ""))
+	if href == "" {
+		// named anchor, the target of #anchor links
+		name := dom.GetAttributeOr(node, "name", dom.GetAttributeOr(node, "id", ""))
+		if name == jumpBreakName {
+			pc.renderJumpBreak(w)
+			return converter.RenderSuccess
+		}
+		if name != "" {
+			w.WriteString("{{< anchor " + strconv.Quote(name) + " >}}")
+		}
+		ctx.RenderChildNodes(ctx, w, node)
+		return converter.RenderSuccess
+	}
 
 	u, err := url.Parse(href)
 	if err != nil {
@@ -271,43 +299,61 @@ func (pc *postConverter) anchorHandler(ctx converter.Context, w converter.Writer
 	}
 
 	// Try to get the referenced image
-	if u.Scheme == "http" || u.Scheme == "https" {
-		baseName := path.Base(u.Path)
-		ext := path.Ext(baseName)
-		if ext == ".jpg" || ext == ".png" {
-			_ = pc.renderImage(ctx, w, href, "")
-			return converter.RenderSuccess
-		}
+	if isImageLink(u) {
+		_ = pc.renderImage(ctx, w, href, "")
+		return converter.RenderSuccess
 	}
 
-	// render the markdown link .
-	w.WriteString(fmt.Sprintf("[%s](%s)", href, href))
-	// // check the link in the background
-	// pc.pushCheckLink(ctx, node, href)
-	return converter.RenderSuccess
+	// A bare link to a YouTube video is rendered as the video
+	if strings.TrimSpace(dom.CollectText(node)) == href && pc.renderYouTube(ctx, w, href) {
+		return converter.RenderSuccess
+	}
+
+	// Documents and audio files are kept in the page bundle
+	if isAttachmentLink(u) && pc.attachments && pc.renderAttachment(ctx, w, node, href) {
+		return converter.RenderSuccess
+	}
+
+	pc.prepareLink(node, u)
+	pc.collectLink(dom.GetAttributeOr(node, "href", href))
+	return converter.RenderTryNext
 }
 
 // Manage <iframe> tags
-// When the iframe embeds a YouTube video, try to get the video in the takeout and render it
+// When the iframe embeds a YouTube video or playlist, try to get the videos in the takeout and render them
 // Otherwise, render a warning
 func (pc *postConverter) iFrameHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
 	src := dom.GetAttributeOr(node, "src", "")
-	// Check if the src is a YouTube embed
-	if strings.Contains(src, "youtube.com/embed/") {
-		return pc.renderYoutubeEmbedded(ctx, w, node)
+	if upload, ok := bloggerVideo(node); ok {
+		return pc.renderBloggerVideo(ctx, w, upload)
+	}
+	if pc.renderYouTube(ctx, w, src) {
+		return converter.RenderSuccess
+	}
+
+	if pc.renderEmbed(w, src) {
+		return converter.RenderSuccess
 	}
 
 	pc.log(w, EXTERNAL_LINK, fmt.Sprintf("iframe pointing to: %s", src), node)
 	w.WriteString("{{< iframe \"" + src + "\" >}}")
-
-	// pc.pushCheckLink(ctx, node, src)
+	pc.collectLink(src)
 	return converter.RenderTryNext
 }
 
 // Manage <object> tags
-// The object reference is an internal blogger ID, but the takeout doesn't contain the object.
-// Issue an error.
+// Videos uploaded to Blogger are searched in the takeout, the players of known providers are
+// rendered with their shortcode. Other objects issue an error.
 func (pc *postConverter) videoObjectHandler(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	if upload, ok := bloggerVideo(node); ok {
+		return pc.renderBloggerVideo(ctx, w, upload)
+	}
+	if pc.renderYouTube(ctx, w, embedSource(node)) {
+		return converter.RenderSuccess
+	}
+	if pc.renderEmbed(w, embedSource(node)) {
+		return converter.RenderSuccess
+	}
 	pc.log(w, UNKNOWN_OBJECT, "can't handle object", node)
 	return converter.RenderSuccess
 }
@@ -319,12 +365,15 @@ func (pc *postConverter) anchorImgHandler(ctx converter.Context, w converter.Wri
 	})
 
 	if img != nil {
-		// src := dom.GetAttributeOr(img, "src", "")
-		src := dom.GetAttributeOr(node, "href", "")
-		if src == "http://picasa.google.com/blogger/" {
+		href := dom.GetAttributeOr(node, "href", "")
+		if href == "http://picasa.google.com/blogger/" {
+			return converter.RenderSuccess
+		}
+		// thumbnail linked to a YouTube video
+		if pc.renderYouTube(ctx, w, href) {
 			return converter.RenderSuccess
 		}
-		err := pc.renderImage(ctx, w, src, "")
+		err := pc.renderLinkedImage(ctx, w, href, dom.GetAttributeOr(img, "src", ""))
 		if err != nil {
 			return converter.RenderTryNext
 		}
@@ -348,14 +397,13 @@ func (pc *postConverter) tableHandler(ctx converter.Context, w converter.Writer,
 		}
 		host := u.Hostname()
 		if strings.HasPrefix(host, "picasaweb.") {
-			pc.log(w, CONTENT_LOST, fmt.Sprintf("The Picasa web albums service has been dismissed by Google: (%s)", u.String()))
-			return converter.RenderSuccess
+			return pc.renderPicasaAlbum(ctx, w, node, u)
 		}
 	}
 
 	// check if the table is of the class "tr-caption-container"
 	if !dom.HasClass(node, "tr-caption-container") {
-		return converter.RenderTryNext // not a table with an image and a caption
+		return pc.renderTable(ctx, w, node) // not a table with an image and a caption
 	}
 
 	img := dom.FindFirstNode(node, func(node *html.Node) bool {
@@ -375,5 +423,5 @@ func (pc *postConverter) tableHandler(ctx converter.Context, w converter.Writer,
 		_ = pc.renderImage(ctx, w, src, caption)
 		return converter.RenderSuccess
 	}
-	return converter.RenderTryNext
+	return pc.renderTable(ctx, w, node)
 }
@@ -0,0 +1,93 @@
+package convert
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bloggerout/internal/filename"
+)
+
+// conversionReport collects the events worth being reviewed after the conversion.
+// The report is written as a Hugo page in the report path.
+type conversionReport struct {
+	mu       sync.Mutex
+	sections map[string][]reportEntry // entries by section title
+}
+
+type reportEntry struct {
+	Subject string // post title, container...
+	Message string
+}
+
+func newConversionReport() *conversionReport {
+	return &conversionReport{
+		sections: make(map[string][]reportEntry),
+	}
+}
+
+// add records an entry in the report's section. It is safe for concurrent use.
+func (r *conversionReport) add(section string, subject string, message string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sections[section] = append(r.sections[section], reportEntry{Subject: subject, Message: message})
+}
+
+// writeReport writes the report page of the blog
+func (bc *blogConverter) writeReport() error {
+	bc.report.mu.Lock()
+	defer bc.report.mu.Unlock()
+	if len(bc.report.sections) == 0 {
+		return nil
+	}
+
+	reportPath, err := prepareFileName(bc.reportPathTmpl, map[string]any{
+		"Blog": filename.Sanitize(bc.blog),
+	})
+	if err != nil {
+		return fmt.Errorf("can't prepare report file name: %w", err)
+	}
+	rpfs, err := openBundle(bc.rfs, reportPath)
+	if err != nil {
+		return fmt.Errorf("can't create report directory: %w", err)
+	}
+	defer rpfs.Close()
+
+	dst, err := rpfs.Create("index.md")
+	if err != nil {
+		return fmt.Errorf("can't create report file: %w", err)
+	}
+	defer dst.Close()
+
+	hp := HugoPost{
+		Blog:  bc.blog,
+		Title: "Conversion report",
+		Date:  time.Now(),
+		Draft: true,
+	}
+	sb := strings.Builder{}
+	for _, section := range slices.Sorted(maps.Keys(bc.report.sections)) {
+		entries := bc.report.sections[section]
+		slices.SortStableFunc(entries, func(a, b reportEntry) int {
+			return strings.Compare(a.Subject, b.Subject)
+		})
+		fmt.Fprintf(&sb, "## %s\n\n", section)
+		sb.WriteString("| | |\n|---|---|\n")
+		for _, e := range entries {
+			fmt.Fprintf(&sb, "| %s | %s |\n", escapeTableCell(e.Subject), escapeTableCell(e.Message))
+		}
+		sb.WriteString("\n")
+	}
+	hp.content = sb.String()
+
+	pc := &postConverter{blogConverter: bc}
+	return pc.Write(dst, hp)
+}
+
+// escapeTableCell makes the text safe for a markdown table cell and for Hugo
+func escapeTableCell(s string) string {
+	return safeText(strings.NewReplacer("|", `\|`, "\n", " ", "\r", "").Replace(s))
+}
@@ -0,0 +1,20 @@
+package convert
+
+import "testing"
+
+func TestEscapeTableCell(t *testing.T) {
+	testCases := []struct {
+		text string
+		want string
+	}{
+		{"Trip", "Trip"},
+		{"A | B", `A \| B`},
+		{"line 1\r\nline 2", "line 1 line 2"},
+		{"Trip {{< x >}}", "Trip {&#123;< x >}}"},
+	}
+	for _, tc := range testCases {
+		if got := escapeTableCell(tc.text); got != tc.want {
+			t.Errorf("escapeTableCell(%q) = %q; want %q", tc.text, got, tc.want)
+		}
+	}
+}
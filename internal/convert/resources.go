@@ -1,6 +1,7 @@
 package convert
 
 import (
+	"bufio"
 	"bytes"
 	"context"
 	"crypto/sha1"
@@ -9,16 +10,30 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"maps"
 	"net/url"
 	"os"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
+	"bloggerout/internal/downloader"
+	"bloggerout/internal/filename"
+	"bloggerout/internal/imaging"
 	"bloggerout/internal/takeout/resources"
 
 	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
 )
 
+const (
+	sanitizedSection = "Sanitized photos"
+	offlineSection   = "Not downloaded (offline)"
+	archiveSection   = "Restored from the Internet Archive"
+	rejectedSection  = "Rejected downloads"
+)
+
 // resource represents an image or a video to be rendered
 type resource struct {
 	Mime     string              // mime type
@@ -27,6 +42,58 @@ type resource struct {
 	Name     string              // base's name
 	Caption  string              // caption
 	data     []byte              // data when encoded in the url
+	ref      string              // reference of the image in the shared media store
+	original string              // original image kept alongside the web copy
+	width    int                 // dimensions of the image, when known
+	height   int
+	link     string // link of the image in the original post
+	lightbox bool   // open the image in a lightbox when clicked
+}
+
+// src returns the figure's source: the image in the post bundle, or in the shared media store
+func (img *resource) src() string {
+	if img.ref != "" {
+		return img.ref
+	}
+	return img.Name
+}
+
+// key identifies the image among the page's media: the takeout resources by container and name,
+// as different albums hold files with the same name, the others by name
+func (img *resource) key() string {
+	if img.Resource != nil {
+		return img.Resource.Container() + "/" + img.Resource.Name()
+	}
+	return img.Name
+}
+
+// uniqueMediaName returns the file name of a media in the page bundle when the name is taken by another media:
+// the name prefixed by the media's folder, as different containers hold files with the same name, else a numbered name
+func uniqueMediaName(name string, r *resources.Resource, taken func(string) bool) string {
+	if !taken(name) {
+		return name
+	}
+	if r != nil {
+		if unique := filename.Sanitize(resources.ContainerFolder(r.Container()) + " " + name); !taken(unique) {
+			return unique
+		}
+	}
+	ext := path.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 2; ; i++ {
+		if unique := fmt.Sprintf("%s-%d%s", base, i, ext); !taken(unique) {
+			return unique
+		}
+	}
+}
+
+// storeKey identifies the image in the shared media store: the takeout resources by container and name,
+// the others by their source
+func (img *resource) storeKey() string {
+	if img.Resource != nil {
+		return img.key()
+	}
+	return img.Source
 }
 
 // renderImage renders an image with a caption
@@ -34,44 +101,104 @@ type resource struct {
 // check if the image is in the referenced takeouts, copy the image on the post
 // otherwise download get the remote image and add a note
 func (pc *postConverter) renderImage(ctx context.Context, w converter.Writer, link string, caption string) error {
-	var img *resource
-	var err error
+	img := pc.imageResource(ctx, w, link)
+	if img == nil {
+		return nil
+	}
+	img.Caption = caption
+	return pc.renderFigure(ctx, w, img)
+}
+
+// renderLinkedImage renders an image wrapped into a link.
+// When the link points to the full size image, the full size image is rendered
+// in a lightbox figure, otherwise the image keeps the link.
+func (pc *postConverter) renderLinkedImage(ctx context.Context, w converter.Writer, href string, src string) error {
+	u, err := url.Parse(href)
+	if err == nil && isImageLink(u) {
+		img := pc.imageResource(ctx, w, href)
+		if img == nil {
+			return nil
+		}
+		img.lightbox = true
+		return pc.renderFigure(ctx, w, img)
+	}
+
+	img := pc.imageResource(ctx, w, src)
+	if img == nil {
+		return nil
+	}
+	if err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		img.link = href
+	}
+	return pc.renderFigure(ctx, w, img)
+}
+
+// imageResource returns the image at the link, looking for it in the takeout.
+// It returns nil when the link is invalid, after logging the problem in the post.
+func (pc *postConverter) imageResource(ctx context.Context, w converter.Writer, link string) *resource {
 	if strings.HasPrefix(link, "data:") {
-		img, err = decodeInlineImage(ctx, link)
+		img, err := decodeInlineImage(ctx, link)
 		if err != nil {
 			pc.log(w, ERROR, fmt.Sprintf("can't decode inline image: %s", err))
 			return nil
 		}
-	} else {
-		u, err := url.Parse(link)
-		if err != nil {
-			pc.log(w, ERROR, fmt.Sprintf("can't parse image link: %.200s", link))
-		}
-		imageName := path.Base(u.Path)
-		imageName = strings.ReplaceAll(imageName, "+", " ")
-		r := pc.data.Resources.SearchByBaseAndDate(imageName, pc.hp.Date)
-		img = &resource{
-			Source:   link,
-			Name:     imageName,
-			Resource: r,
-		}
+		return img
+	}
+
+	u, err := url.Parse(link)
+	if err != nil {
+		pc.log(w, ERROR, fmt.Sprintf("can't parse image link: %.200s", link))
+		return nil
 	}
+	r := pc.data.Resources.SearchByBaseAndDate(strings.ReplaceAll(path.Base(u.Path), "+", " "), pc.hp.Date)
+	return &resource{
+		Source:   link,
+		Name:     imageName(u),
+		Resource: r,
+	}
+}
 
+// renderTakeoutImage renders an image found in the takeout, like the photos of an album
+func (pc *postConverter) renderTakeoutImage(ctx context.Context, w converter.Writer, r *resources.Resource, caption string) error {
+	return pc.renderFigure(ctx, w, &resource{
+		Source:   r.Name(),
+		Name:     filename.Sanitize(r.Name()),
+		Resource: r,
+		Caption:  caption,
+	})
+}
+
+// renderFigure copies the image into the post and writes the figure shortcode
+func (pc *postConverter) renderFigure(ctx context.Context, w converter.Writer, img *resource) error {
+	img.sniffName()
 	// don't render duplicated images in the same post
-	if _, exists := pc.resources[img.Name]; exists {
+	if _, exists := pc.resources[img.key()]; exists {
 		return nil
 	}
-	img.Caption = caption
-	pc.resources[img.Name] = img // remember we have processed this image already
+	if pc.store == nil {
+		img.Name = uniqueMediaName(img.Name, img.Resource, func(name string) bool {
+			for _, other := range pc.resources {
+				if other.Name == name && other.key() != img.key() {
+					return true
+				}
+			}
+			return false
+		})
+	}
+	if img.Caption == "" && img.Resource != nil {
+		// use the description given in Google Photos when the post has no caption
+		img.Caption = img.Resource.Metadata().Description
+	}
+	pc.resources[img.key()] = img // remember we have processed this image already
 
-	_, err = pc.pfs.Stat(img.Name)
-	if err != nil && !errors.Is(err, os.ErrNotExist) {
+	saved, err := pc.isImageSaved(img)
+	if err != nil {
 		pc.log(w, ERROR, fmt.Sprintf("can't stat image: %s", err))
 		return err
 	}
 
-	if err != nil && errors.Is(err, os.ErrNotExist) {
-		// the image is not yet in the post folder
+	if !saved {
+		// the image is not yet in the post folder, or in the media store
 
 		if img.Resource == nil {
 			// Not in the takeout, try to download from the internet
@@ -100,23 +227,66 @@ func (pc *postConverter) renderImage(ctx context.Context, w converter.Writer, li
 			}
 		}
 	}
+	pc.resources[img.key()] = img // the downloaded image may have been renamed after its content
+
+	if img.Resource != nil && pc.countUses {
+		pc.data.Resources.Use(img.Resource)
+	}
+
 	// write the figure shortcode
 	sb := strings.Builder{}
-	sb.WriteString("{{< figure")
+	if img.lightbox {
+		sb.WriteString("{{< lightbox")
+	} else {
+		sb.WriteString("{{< figure")
+	}
 	sb.WriteString(" src=")
-	sb.WriteString(safeAttribute(img.Name))
+	sb.WriteString(safeAttribute(img.src()))
 	sb.WriteString(" alt=")
 	sb.WriteString(fmt.Sprintf("%q", img.Name))
 	if img.Caption != "" {
 		sb.WriteString(" caption=")
 		sb.WriteString(safeAttribute(img.Caption))
 	}
+	if img.width > 0 && img.height > 0 {
+		sb.WriteString(fmt.Sprintf(" width=\"%d\" height=\"%d\"", img.width, img.height))
+	}
+	if img.original != "" {
+		sb.WriteString(" link=")
+		sb.WriteString(safeAttribute(img.original))
+	} else if img.link != "" {
+		sb.WriteString(" link=")
+		sb.WriteString(safeAttribute(img.link))
+	}
+	sb.WriteString(img.metadataAttributes(pc.stripPrivate))
 	sb.WriteString(" >}}\n")
 	w.WriteString(sb.String())
 
 	return nil
 }
 
+// metadataAttributes returns the figure attributes for the capture date and the GPS location
+// found in the takeout's metadata. The location is left out when the private data are stripped,
+// the names of the people tagged on the photo are never published.
+func (img *resource) metadataAttributes(stripPrivate bool) string {
+	if img.Resource == nil {
+		return ""
+	}
+	md := img.Resource.Metadata()
+	sb := strings.Builder{}
+	if !md.PhotoTakenTime.IsZero() {
+		sb.WriteString(" date=")
+		sb.WriteString(safeAttribute(md.PhotoTakenTime.Format(time.RFC3339)))
+	}
+	if !md.GeoData.IsZero() && !stripPrivate {
+		sb.WriteString(" lat=")
+		sb.WriteString(safeAttribute(strconv.FormatFloat(md.GeoData.Latitude, 'f', -1, 64)))
+		sb.WriteString(" lon=")
+		sb.WriteString(safeAttribute(strconv.FormatFloat(md.GeoData.Longitude, 'f', -1, 64)))
+	}
+	return sb.String()
+}
+
 // decodeInlineImage decodes an inline image and returns the image with the image
 // name set with the SHA1 of the content.
 //
@@ -127,8 +297,7 @@ func decodeInlineImage(_ context.Context, link string) (*resource, error) {
 		return nil, fmt.Errorf("invalid data URL: %.200s", link)
 	}
 
-	var ext string
-	ext, source, found = strings.Cut(source, ";")
+	_, source, found = strings.Cut(source, ";")
 	if !found {
 		return nil, fmt.Errorf("invalid data URL: %.200s", link)
 	}
@@ -143,8 +312,7 @@ func decodeInlineImage(_ context.Context, link string) (*resource, error) {
 	hasher := sha1.New()
 	hasher.Write([]byte(source))
 	hash := hasher.Sum(nil)
-	img.Name = fmt.Sprintf("%x.%s", hash, ext)
-	img.Source = img.Name
+	img.Source = fmt.Sprintf("%x", hash)
 
 	// decode the image into img.data
 	var err error
@@ -153,99 +321,209 @@ func decodeInlineImage(_ context.Context, link string) (*resource, error) {
 	if err != nil {
 		return nil, fmt.Errorf("invalid data URL: %.200s", link)
 	}
+	// the declared type isn't trusted, the extension is given by the content
+	img.Mime = sniffMedia(img.data)
+	img.Name = withMediaExtension(img.Source, img.Mime)
 	return img, nil
 }
 
-// // submit the check link task
-// func (pc *postConverter) pushCheckLink(ctx context.Context, link string) error {
-// 	u, err := url.Parse(link)
-// 	if err != nil {
-// 		return err
-// 	}
-
-// 	pc.submit(ctx, func(ctx context.Context) {
-// 		switch u.Scheme {
-// 		case "http", "https":
-// 			pc.downloader.CheckLink(ctx, link, func(url string, status int, err error) {
-// 				if u.Host != "blogger.googleusercontent.com" {
-// 					pc.log(w, EXTERNAL_LINK, fmt.Sprintf("External link,  HTTP Status:%d,  Link: %.200s", status, link), tag)
-// 					return
-// 				}
-// 				pc.log("WARNING", fmt.Sprintf("Internal link,  HTTP Status:%d,  Link: %.200s", status, link), tag)
-// 			})
-// 		default:
-// 			pc.log(w, ERROR, fmt.Sprintf("Unknown scheme:%s, Link: %.200s", u.Scheme, link), tag)
-// 		}
-// 	})
-// 	return nil
-// }
+// isImageSaved checks if the image is already in the post folder,
+// or in the shared media store
+func (pc *postConverter) isImageSaved(img *resource) (bool, error) {
+	if pc.store != nil {
+		si, ok := pc.store.lookup(img.storeKey())
+		img.ref, img.original, img.width, img.height = si.ref, si.original, si.width, si.height
+		return ok, nil
+	}
+	names := []string{img.Name}
+	if img.Resource == nil && img.data == nil {
+		// the content of a download is known once downloaded, the image may have been renamed after it
+		for _, mime := range slices.Sorted(maps.Keys(mediaExtensions)) {
+			if name := withMediaExtension(img.Name, mime); !slices.Contains(names, name) {
+				names = append(names, name)
+			}
+		}
+	}
+	for _, name := range names {
+		_, err := pc.pfs.Stat(name)
+		if err == nil {
+			img.Name = name
+			return true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
 
-// submit an image download
+// sniffName names the takeout and inline images after their content,
+// before looking for them in the page
+func (img *resource) sniffName() {
+	var head []byte
+	switch {
+	case img.data != nil:
+		head = img.data
+	case img.Resource != nil:
+		f, err := img.Resource.Open()
+		if err != nil {
+			return
+		}
+		defer f.Close()
+		head = make([]byte, 512)
+		n, _ := io.ReadFull(f, head)
+		head = head[:n]
+	default:
+		return
+	}
+	img.Mime = sniffMedia(head)
+	img.Name = withMediaExtension(img.Name, img.Mime)
+}
 
-func (pc *postConverter) ImageDownload(ctx context.Context, resource *resource) error {
-	file, err := pc.pfs.Create(resource.Name)
-	if err != nil {
-		slog.Error("Failed to create file", "file", resource.Name, "error", err)
-		return err
+// saveImage writes the image into the post folder, or into the shared media store
+func (pc *postConverter) saveImage(img *resource, r io.Reader) error {
+	// name the file after its actual content, URLs and takeouts can't be trusted
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	img.Mime = sniffMedia(head)
+	img.Name = withMediaExtension(img.Name, img.Mime)
+	r = br
+
+	if pc.resize > 0 || pc.stripPrivate {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		if pc.stripPrivate {
+			data = pc.sanitizeImage(img, data)
+		}
+		if pc.resize > 0 {
+			data, err = pc.processImage(img, data)
+			if err != nil {
+				return err
+			}
+		}
+		r = bytes.NewReader(data)
 	}
-	defer file.Close()
-	err = pc.downloader.DownloadFile(ctx, resource.Source, file)
-	if err != nil {
-		slog.Error("Failed to download file", "file", resource.Name, "error", err)
-		defer pc.pfs.Remove(resource.Name) // Remove the file if the download fails, to avoid leaving an empty file in the post's directory
-		return err
+
+	if pc.store != nil {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return err
+		}
+		img.ref, err = pc.store.save(path.Ext(img.Name), data)
+		if err != nil {
+			return err
+		}
+		pc.store.remember(img.storeKey(), storedImage{ref: img.ref, original: img.original, width: img.width, height: img.height})
+		return nil
 	}
-	return nil
-}
 
-func (pc *postConverter) copyFromInternet(ctx context.Context, img *resource) error {
 	dest, err := pc.pfs.Create(img.Name)
 	if err != nil {
-		return fmt.Errorf("can't create file %s: %w", img.Name, err)
+		return fmt.Errorf("failed to create image %s: %w", img.Name, err)
 	}
 	defer dest.Close()
-
-	err = pc.downloader.DownloadFile(ctx, img.Source, dest)
+	_, err = io.Copy(dest, r)
 	if err != nil {
-		return fmt.Errorf("can't download image %s: %w", img.Name, err)
+		defer pc.pfs.Remove(img.Name) // don't leave a truncated image in the post's directory
+		return fmt.Errorf("failed to copy image into %q: %w", img.Name, err)
 	}
 	return nil
 }
 
-func (pc *postConverter) copyFromTakeout(_ context.Context, img *resource) error {
-	dest, err := pc.pfs.Create(img.Name)
+// processImage applies the EXIF orientation and produces the web copy of the image.
+// The original is saved alongside when requested.
+// Formats not handled by the processing are returned untouched.
+func (pc *postConverter) processImage(img *resource, data []byte) ([]byte, error) {
+	res, err := imaging.Process(data, imaging.Options{MaxDimension: pc.resize, Quality: pc.quality})
 	if err != nil {
-		return fmt.Errorf("failed to create image %s: %w", img.Name, err)
+		slog.Debug("image not processed", "image", img.Name, "error", err)
+		return data, nil
+	}
+	img.width, img.height = res.Width, res.Height
+	if res.Processed && res.Format == "jpeg" {
+		// the encoder drops the EXIF data, keep at least the capture date
+		res.Data = imaging.CopyCaptureDate(data, res.Data)
+	}
+	if !res.Processed || !pc.keepOriginal {
+		return res.Data, nil
 	}
-	defer dest.Close()
 
-	src, err := img.Resource.Open()
+	ext := path.Ext(img.Name)
+	if pc.store != nil {
+		img.original, err = pc.store.save(ext, data)
+		return res.Data, err
+	}
+	img.original = strings.TrimSuffix(img.Name, ext) + ".original" + ext
+	dest, err := pc.pfs.Create(img.original)
 	if err != nil {
-		return fmt.Errorf("can't open image %q in the takeout: %w", img.Name, err)
+		return nil, fmt.Errorf("failed to create image %s: %w", img.original, err)
 	}
-	defer src.Close()
-	_, err = io.Copy(dest, src)
+	defer dest.Close()
+	_, err = dest.Write(data)
 	if err != nil {
-		return fmt.Errorf("failed to copy image into %q: %w", img.Name, err)
+		return nil, fmt.Errorf("failed to write image %s: %w", img.original, err)
 	}
-	return nil
+	return res.Data, nil
 }
 
-func (pc *postConverter) copyFromURL(_ context.Context, img *resource) error {
-	// Create the destination directory if it doesn't exist
-	dest, err := pc.pfs.Create(img.Name)
-	if err != nil {
-		return fmt.Errorf("failed to create image %s: %w", img.Name, err)
+// sanitizeImage removes the private EXIF data of a JPEG image, and reports it
+func (pc *postConverter) sanitizeImage(img *resource, data []byte) []byte {
+	data, removed := imaging.Sanitize(data)
+	if len(removed) > 0 {
+		pc.report.add(sanitizedSection, pc.hp.Title, img.Name+": "+strings.Join(removed, ", "))
 	}
-	defer dest.Close()
+	return data
+}
+
+// submit an image download
 
-	_, err = io.Copy(dest, bytes.NewReader(img.data))
+func (pc *postConverter) ImageDownload(ctx context.Context, resource *resource) error {
+	buf := bytes.Buffer{}
+	err := pc.downloader.DownloadFile(ctx, resource.Source, &buf)
+	var rejected *downloader.RejectedError
+	if errors.As(err, &rejected) {
+		pc.report.add(rejectedSection, pc.hp.Title, rejected.Reason+": "+rejected.URL)
+	}
+	if err != nil && pc.archiveFallback {
+		buf.Reset()
+		snapshot, err2 := pc.downloader.DownloadArchived(ctx, resource.Source, pc.hp.Date, &buf)
+		if err2 == nil {
+			pc.report.add(archiveSection, pc.hp.Title, resource.Source+" restored from "+snapshot)
+			err = nil
+		} else {
+			err = fmt.Errorf("%w, archive: %w", err, err2)
+		}
+	}
 	if err != nil {
-		return fmt.Errorf("failed to copy image %s: %w", img.Name, err)
+		if errors.Is(err, downloader.ErrOffline) {
+			pc.report.add(offlineSection, pc.hp.Title, resource.Source)
+		}
+		slog.Error("Failed to download file", "file", resource.Name, "error", err)
+		return err
+	}
+	err = pc.saveImage(resource, &buf)
+	if err != nil {
+		slog.Error("Failed to save file", "file", resource.Name, "error", err)
+		return err
 	}
 	return nil
 }
 
+func (pc *postConverter) copyFromTakeout(_ context.Context, img *resource) error {
+	src, err := img.Resource.Open()
+	if err != nil {
+		return fmt.Errorf("can't open image %q in the takeout: %w", img.Name, err)
+	}
+	defer src.Close()
+	return pc.saveImage(img, src)
+}
+
+func (pc *postConverter) copyFromURL(_ context.Context, img *resource) error {
+	return pc.saveImage(img, bytes.NewReader(img.data))
+}
+
 // video represents an video to be rendered
 type video struct {
 	Resource *resources.Resource // image resource
@@ -253,6 +531,9 @@ type video struct {
 	Source   string              // image source in takeout or the web URL
 	Name     string              // image base's name
 	Caption  string              // image caption
+	Title    string              // video title
+	Duration time.Duration       // video duration, 0 when unknown
+	Poster   string              // path of the thumbnail in the YouTube takeout, empty when unknown
 }
 
 // shortcode for a HTML5 video player
@@ -270,6 +551,9 @@ func (pc *postConverter) renderVideo(ctx context.Context, w converter.Writer, vi
 		slog.Error("failed to copy video from takeout", "error", err)
 		return err
 	}
+	if pc.countUses {
+		pc.data.Resources.Use(video.Resource)
+	}
 
 	sb.WriteString("{{< media/video")
 	sb.WriteString(" src=")
@@ -281,6 +565,23 @@ func (pc *postConverter) renderVideo(ctx context.Context, w converter.Writer, vi
 		sb.WriteString(" caption=")
 		sb.WriteString(safeAttribute(video.Caption))
 	}
+	if video.Title != "" {
+		sb.WriteString(" title=")
+		sb.WriteString(safeAttribute(video.Title))
+	}
+	if video.Duration > 0 {
+		sb.WriteString(" duration=")
+		sb.WriteString(safeAttribute(formatDuration(video.Duration)))
+	}
+	if video.Poster != "" {
+		poster, err := pc.copyPoster(video)
+		if err != nil {
+			slog.Error("failed to copy video thumbnail from takeout", "error", err)
+		} else {
+			sb.WriteString(" poster=")
+			sb.WriteString(safeAttribute(strings.TrimPrefix(poster, "static")))
+		}
+	}
 
 	sb.WriteString(" >}}\n")
 	w.WriteString(sb.String())
@@ -309,6 +610,45 @@ func (pc *postConverter) compyVideoFromTakeout(_ context.Context, video video) e
 	return nil
 }
 
+// copyPoster copies the video thumbnail next to the video, and returns its name
+func (pc *postConverter) copyPoster(video video) (string, error) {
+	name := strings.TrimSuffix(video.Name, path.Ext(video.Name)) + "-poster" + strings.ToLower(path.Ext(video.Poster))
+	src, err := pc.data.YouTube.Open(video.Poster)
+	if err != nil {
+		return "", fmt.Errorf("can't open thumbnail %q in the takeout: %w", video.Poster, err)
+	}
+	defer src.Close()
+	dest, err := pc.pfs.Create(name)
+	if err != nil {
+		return "", fmt.Errorf("failed to create thumbnail %s: %w", name, err)
+	}
+	defer dest.Close()
+	_, err = io.Copy(dest, src)
+	if err != nil {
+		defer pc.pfs.Remove(name)
+		return "", fmt.Errorf("failed to copy thumbnail into %q: %w", name, err)
+	}
+	return name, nil
+}
+
+// formatDuration gives the duration as displayed by video players: 1:02:03, 2:03
+func formatDuration(d time.Duration) string {
+	d = d.Round(time.Second)
+	h, m, s := int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60
+	if h > 0 {
+		return fmt.Sprintf("%d:%02d:%02d", h, m, s)
+	}
+	return fmt.Sprintf("%d:%02d", m, s)
+}
+
+// shortcodeDelimiters breaks the shortcode delimiters with an HTML entity, rendered as a brace
+var shortcodeDelimiters = strings.NewReplacer("{{", "{&#123;")
+
+// safeText escapes a plain text written in the Markdown content, so Hugo doesn't take it for a shortcode
+func safeText(s string) string {
+	return shortcodeDelimiters.Replace(s)
+}
+
 // safeAttribute escapes a string for use in an HTML attribute
 func safeAttribute(s string) string {
 	return `"` + strings.ReplaceAll(s, `"`, `\"`) + `"`
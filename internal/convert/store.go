@@ -0,0 +1,100 @@
+package convert
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+)
+
+// mediaStore keeps a single copy of the images shared by all posts of a blog.
+// Images are named after the hash of their content, so an image used by several
+// posts is stored once.
+type mediaStore struct {
+	mu       sync.Mutex
+	rfs      *os.Root               // blog's root
+	dir      string                 // store directory inside the blog's root
+	url      string                 // URL of the store directory in the site
+	bySource map[string]storedImage // images by source: container and name, or URL
+	stored   map[string]bool        // names of the stored images
+}
+
+// storedImage describes an image saved in the store
+type storedImage struct {
+	ref           string // reference of the image
+	original      string // reference of the original image, when kept
+	width, height int    // dimensions, when known
+}
+
+// newMediaStore creates the store directory.
+// Directories under /static and /assets are served from the site root.
+func newMediaStore(rfs *os.Root, dir string) (*mediaStore, error) {
+	dir = strings.Trim(dir, "/")
+	err := mkDirAll(rfs, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	url := dir
+	for _, prefix := range []string{"static/", "assets/"} {
+		if strings.HasPrefix(url, prefix) {
+			url = strings.TrimPrefix(url, prefix)
+			break
+		}
+	}
+	return &mediaStore{
+		rfs:      rfs,
+		dir:      dir,
+		url:      "/" + url,
+		bySource: make(map[string]storedImage),
+		stored:   make(map[string]bool),
+	}, nil
+}
+
+// lookup returns the image already stored from this source
+func (ms *mediaStore) lookup(source string) (storedImage, bool) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	si, ok := ms.bySource[source]
+	return si, ok
+}
+
+// remember records the image stored for the source
+func (ms *mediaStore) remember(source string, si storedImage) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	ms.bySource[source] = si
+}
+
+// save stores the image when its content isn't yet in the store, and returns its reference
+func (ms *mediaStore) save(ext string, data []byte) (string, error) {
+	hash := sha256.Sum256(data)
+	name := hex.EncodeToString(hash[:]) + strings.ToLower(ext)
+	ref := path.Join(ms.url, name)
+
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if !ms.stored[name] {
+		if _, err := ms.rfs.Stat(path.Join(ms.dir, name)); err != nil {
+			if !os.IsNotExist(err) {
+				return "", err
+			}
+			f, err := ms.rfs.Create(path.Join(ms.dir, name))
+			if err != nil {
+				return "", fmt.Errorf("can't create media %s: %w", name, err)
+			}
+			_, err = f.Write(data)
+			err = errors.Join(err, f.Close())
+			if err != nil {
+				ms.rfs.Remove(path.Join(ms.dir, name))
+				return "", fmt.Errorf("can't write media %s: %w", name, err)
+			}
+		}
+		ms.stored[name] = true
+	}
+	return ref, nil
+}
@@ -0,0 +1,108 @@
+package convert
+
+import (
+	"bytes"
+	"context"
+	"io/fs"
+	"os"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+)
+
+func TestMediaStore(t *testing.T) {
+	rfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+
+	ms, err := newMediaStore(rfs, "/assets/media/")
+	if err != nil {
+		t.Fatalf("can't create the media store: %v", err)
+	}
+
+	ref1, err := ms.save(".JPG", []byte("same content"))
+	if err != nil {
+		t.Fatalf("can't save: %v", err)
+	}
+	ref2, err := ms.save(".jpg", []byte("same content"))
+	if err != nil {
+		t.Fatalf("can't save: %v", err)
+	}
+	if ref1 != ref2 {
+		t.Errorf("same content must give the same reference: %q, %q", ref1, ref2)
+	}
+	if !strings.HasPrefix(ref1, "/media/") || !strings.HasSuffix(ref1, ".jpg") || len(ref1) != len("/media/")+64+len(".jpg") {
+		t.Errorf("unexpected reference %q", ref1)
+	}
+
+	entries, err := os.ReadDir(rfs.Name() + "/assets/media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected one file in the store, got %d", len(entries))
+	}
+
+	ms.remember("https://example.com/b/photo.jpg", storedImage{ref: ref2})
+	if si, ok := ms.lookup("https://example.com/b/photo.jpg"); !ok || si.ref != ref1 {
+		t.Errorf("lookup by source failed: %q, %v", si.ref, ok)
+	}
+	if _, ok := ms.lookup("https://example.com/c/photo.jpg"); ok {
+		t.Errorf("unexpected source found")
+	}
+}
+
+func TestMediaStoreSameName(t *testing.T) {
+	vfs := fstest.MapFS{
+		"Holidays/IMG_0001.gif": &fstest.MapFile{Data: []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")},
+		"Party/IMG_0001.gif":    &fstest.MapFile{Data: []byte("GIF89a\x03\x00\x03\x00\x00\x00\x00;")},
+	}
+	rs := resources.New()
+	for _, container := range []string{"Holidays", "Party"} {
+		entries, err := fs.ReadDir(vfs, container)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rs.Add(vfs, container, container, entries[0], nil)
+	}
+	rfs, err := os.OpenRoot(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rfs.Close()
+	ms, err := newMediaStore(rfs, "/static/media/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := &blogConverter{Convert: &Convert{}, data: &takeout.Takeout{Resources: rs}, report: newConversionReport(), store: ms}
+
+	// each post shows the photo of its own album
+	src := regexp.MustCompile(`src="([^"]+)"`)
+	refs := map[string]bool{}
+	for _, container := range []string{"Holidays", "Party", "Holidays"} {
+		pc := &postConverter{blogConverter: bc, errors: map[string]int{}, resources: map[string]*resource{}}
+		var buf bytes.Buffer
+		err := pc.renderTakeoutImage(context.Background(), &buf, rs.SearchInContainer(container, "IMG_0001.gif"), "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m := src.FindStringSubmatch(buf.String())
+		if m == nil {
+			t.Fatalf("no image rendered: %s", buf.String())
+		}
+		refs[m[1]] = true
+	}
+	if len(refs) != 2 {
+		t.Errorf("expected a reference per album, got %v", refs)
+	}
+	entries, err := os.ReadDir(rfs.Name() + "/static/media")
+	if err != nil || len(entries) != 2 {
+		t.Errorf("expected 2 files in the store, got %d: %v", len(entries), err)
+	}
+}
@@ -0,0 +1,67 @@
+package convert
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+)
+
+// Blogger marks the end of the post summary with a <!--more--> comment in recent posts,
+// and with a <a name="more"></a> anchor in the published HTML of older ones.
+const (
+	jumpBreakName  = "more"
+	summaryDivider = "<!--more-->"
+)
+
+var jumpBreakComment = regexp.MustCompile(`(?i)<!--\s*more\s*-->`)
+
+// markJumpBreak replaces the <!--more--> comments, dropped by the HTML parser,
+// by the <a name="more"> anchor handled by anchorHandler
+func markJumpBreak(content string) string {
+	return jumpBreakComment.ReplaceAllString(content, `<a name="more"></a>`)
+}
+
+// renderJumpBreak writes Hugo's summary divider at the first jump break of the post
+func (pc *postConverter) renderJumpBreak(w converter.Writer) {
+	if pc.jumpBreak {
+		return
+	}
+	pc.jumpBreak = true
+	w.WriteString("\n\n" + summaryDivider + "\n\n")
+}
+
+var (
+	closingShortcode = regexp.MustCompile(`\{\{[<%]\s*/\s*([^\s>%]+)\s*[>%]\}\}`)
+	shortcode        = regexp.MustCompile(`(?s)\{\{[<%].*?[>%]\}\}`)
+	blankLines       = regexp.MustCompile(`\n\s*\n(\s*\n)+`)
+)
+
+// summaryOf returns the text preceding the summary divider, without the shortcodes,
+// or an empty string when the post has no divider
+func summaryOf(content string) string {
+	before, _, found := strings.Cut(content, summaryDivider)
+	if !found {
+		return ""
+	}
+	return strings.TrimSpace(blankLines.ReplaceAllString(stripShortcodes(before), "\n\n"))
+}
+
+// stripShortcodes removes the shortcodes, with the content of the paired ones like tables and galleries
+func stripShortcodes(s string) string {
+	for {
+		m := closingShortcode.FindStringSubmatchIndex(s)
+		if m == nil {
+			break
+		}
+		name := s[m[2]:m[3]]
+		start := m[0]
+		for _, o := range shortcode.FindAllStringIndex(s[:m[0]], -1) {
+			if fields := strings.Fields(strings.Trim(s[o[0]+3:o[1]-3], " ")); len(fields) > 0 && fields[0] == name {
+				start = o[0]
+			}
+		}
+		s = s[:start] + s[m[1]:]
+	}
+	return shortcode.ReplaceAllString(s, "")
+}
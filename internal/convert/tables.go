@@ -0,0 +1,167 @@
+package convert
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/JohannesKaufmann/dom"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"golang.org/x/net/html"
+)
+
+// tableKind tells how a table is converted
+type tableKind int
+
+const (
+	simpleTable  tableKind = iota // GFM pipe table, rendered by the table plugin
+	layoutTable                   // table positioning images or text, its cells are rendered one after the other
+	complexTable                  // spans, nested tables or multi-line cells, kept as HTML
+)
+
+// classifyTable checks if the table holds tabular data that fits in a pipe table
+func classifyTable(node *html.Node) tableKind {
+	if dom.GetAttributeOr(node, "role", "") == "presentation" {
+		return layoutTable
+	}
+
+	kind := simpleTable
+	cells, columns := 0, 0
+	for _, tr := range dom.FindAllNodes(node, func(n *html.Node) bool { return dom.NodeName(n) == "tr" }) {
+		n := 0
+		for c := tr.FirstChild; c != nil; c = c.NextSibling {
+			name := dom.NodeName(c)
+			if name != "td" && name != "th" {
+				continue
+			}
+			n++
+			if hasMedia(c) {
+				return layoutTable
+			}
+			if span(c, "colspan") > 1 || span(c, "rowspan") > 1 || !isSimpleCell(c) {
+				kind = complexTable
+			}
+		}
+		cells += n
+		columns = max(columns, n)
+	}
+	if cells <= 1 || columns <= 1 {
+		// a box around the content, not a table
+		return layoutTable
+	}
+	return kind
+}
+
+// span returns the value of the colspan or rowspan attribute
+func span(cell *html.Node, attr string) int {
+	n, err := strconv.Atoi(strings.TrimSpace(dom.GetAttributeOr(cell, attr, "1")))
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// hasMedia checks if the node contains images or embedded content
+func hasMedia(node *html.Node) bool {
+	return dom.FindFirstNode(node, func(n *html.Node) bool {
+		switch dom.NodeName(n) {
+		case "img", "iframe", "object", "embed", "video":
+			return true
+		}
+		return false
+	}) != nil
+}
+
+// isSimpleCell checks if the cell content fits on a single line
+func isSimpleCell(cell *html.Node) bool {
+	blocks := 0
+	multiline := dom.FindFirstNode(cell, func(n *html.Node) bool {
+		switch dom.NodeName(n) {
+		case "table", "br", "ul", "ol", "pre", "hr", "blockquote", "h1", "h2", "h3", "h4", "h5", "h6":
+			return true
+		case "p", "div":
+			blocks++
+		}
+		return blocks > 1
+	})
+	return multiline == nil
+}
+
+// renderTable converts the tables that aren't image captions or Picasa albums
+func (pc *postConverter) renderTable(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	switch classifyTable(node) {
+	case layoutTable:
+		return pc.renderLayoutTable(ctx, w, node)
+	case complexTable:
+		return pc.renderComplexTable(ctx, w, node)
+	}
+	return converter.RenderTryNext // the table plugin writes the pipe table
+}
+
+// renderLayoutTable renders the content of the cells as successive blocks
+func (pc *postConverter) renderLayoutTable(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	for _, cell := range dom.FindAllNodes(node, func(n *html.Node) bool {
+		name := dom.NodeName(n)
+		return (name == "td" || name == "th") && !insideCell(n, node)
+	}) {
+		w.WriteString("\n\n")
+		ctx.RenderChildNodes(ctx, w, cell)
+		w.WriteString("\n\n")
+	}
+	return converter.RenderSuccess
+}
+
+// insideCell checks if the node is nested in a cell of the table
+func insideCell(n *html.Node, table *html.Node) bool {
+	for p := n.Parent; p != nil && p != table; p = p.Parent {
+		if name := dom.NodeName(p); name == "td" || name == "th" {
+			return true
+		}
+	}
+	return false
+}
+
+// tableAttributes lists the attributes kept in the HTML of complex tables
+var tableAttributes = map[string]bool{
+	"colspan": true, "rowspan": true, "href": true, "title": true, "scope": true,
+}
+
+// renderComplexTable renders the table as HTML inside the table shortcode,
+// without the presentation attributes of the Blogger theme.
+// The complex tables have no images, they are layout tables.
+func (pc *postConverter) renderComplexTable(_ converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
+	sb := strings.Builder{}
+	err := html.Render(&sb, pc.cleanTable(node))
+	if err != nil {
+		pc.log(w, ERROR, "can't render table: "+err.Error())
+		return converter.RenderSuccess
+	}
+	w.WriteString("\n\n{{< table >}}\n")
+	w.WriteString(safeText(sb.String())) // the entity is decoded in the texts and the attributes
+	w.WriteString("\n{{< /table >}}\n\n")
+	return converter.RenderSuccess
+}
+
+// cleanTable returns a copy of the table without the style attributes and the comments,
+// with its links prepared and collected like the other links of the post
+func (pc *postConverter) cleanTable(node *html.Node) *html.Node {
+	c := &html.Node{Type: node.Type, Data: node.Data, DataAtom: node.DataAtom}
+	for _, a := range node.Attr {
+		if tableAttributes[a.Key] {
+			c.Attr = append(c.Attr, a)
+		}
+	}
+	for child := node.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.CommentNode {
+			continue
+		}
+		c.AppendChild(pc.cleanTable(child))
+	}
+	if href := strings.TrimSpace(dom.GetAttributeOr(c, "href", "")); dom.NodeName(c) == "a" && href != "" {
+		if u, err := url.Parse(href); err == nil {
+			pc.prepareLink(c, u)
+			pc.collectLink(dom.GetAttributeOr(c, "href", href))
+		}
+	}
+	return c
+}
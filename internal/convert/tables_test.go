@@ -0,0 +1,92 @@
+package convert
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/table"
+)
+
+func TestTableHandler(t *testing.T) {
+	testCases := []struct {
+		name string
+		html string
+		want []string // parts of the result
+	}{
+		{
+			"simple",
+			`<table style="width:100%"><tr><td>Flour</td><td><b>200</b> g</td></tr><tr><td>Sugar</td><td>50 g</td></tr></table>`,
+			[]string{"| Flour | **200** g |", "|-------|", "| Sugar | 50 g "},
+		},
+		{
+			"header",
+			`<table><thead><tr><th>Day</th><th>Stage</th></tr></thead><tr><td>1</td><td>Paris - Lyon</td></tr></table>`,
+			[]string{"| Day | Stage", "| 1   | Paris - Lyon |"},
+		},
+		{
+			"colspan",
+			`<table class="x"><tr><td colspan="2" style="color:red">Menu</td></tr><tr><td>A</td><td>B</td></tr></table>`,
+			[]string{"{{< table >}}", `<td colspan="2">Menu</td>`, "{{< /table >}}"},
+		},
+		{
+			"multiline",
+			`<table><tr><td>Day 1</td><td>Paris<br>Lyon</td></tr><tr><td>Day 2</td><td>Nice</td></tr></table>`,
+			[]string{"{{< table >}}", "Paris<br/>Lyon"},
+		},
+		{
+			"nested",
+			`<table><tr><td>A</td><td><table><tr><td>x</td><td>y</td></tr></table></td></tr></table>`,
+			[]string{"{{< table >}}", "<td>x</td>"},
+		},
+		{
+			"links",
+			`<table><tr><td colspan="2"><a href="/2010/05/trip.html">Trip</a> {{< x >}}</td></tr><tr><td>A</td><td>B</td></tr></table>`,
+			[]string{`<a href="https://example.blogspot.com/2010/05/trip.html">Trip</a> {&#123;&lt; x &gt;}}`},
+		},
+		{
+			"layout",
+			`<table><tr><td><p>Some text in a box</p></td></tr></table>`,
+			[]string{"Some text in a box"},
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pc := &postConverter{
+				blogConverter: &blogConverter{Convert: &Convert{linkCheck: true}, report: newConversionReport(), links: newLinkSet()},
+				errors:        map[string]int{},
+				url:           "https://example.blogspot.com/2010/06/post.html",
+				path:          "content/posts/post",
+			}
+			mdc := converter.NewConverter(converter.WithPlugins(
+				base.NewBasePlugin(),
+				commonmark.NewCommonmarkPlugin(),
+				table.NewTablePlugin(table.WithHeaderPromotion(true)),
+			))
+			mdc.Register.RendererFor("table", converter.TagTypeInline, pc.tableHandler, converter.PriorityEarly)
+
+			got, err := mdc.ConvertString(tc.html)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, part := range tc.want {
+				if !strings.Contains(got, part) {
+					t.Errorf("%q not found in:\n%s", part, got)
+				}
+			}
+			for link := range pc.links.refs {
+				if !strings.Contains(got, `href="`+link+`"`) {
+					t.Errorf("unexpected link %s collected", link)
+				}
+			}
+			if strings.Contains(got, "<a href") && len(pc.links.refs) == 0 {
+				t.Errorf("links not collected")
+			}
+			if strings.Contains(got, "style") || strings.Contains(got, "<table") != strings.Contains(got, "{{< table >}}") {
+				t.Errorf("unexpected result:\n%s", got)
+			}
+		})
+	}
+}
@@ -1,53 +1,163 @@
 package convert
 
 import (
+	"context"
 	"fmt"
 	"net/url"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
 
 	"bloggerout/internal/filename"
+	"bloggerout/internal/takeout/youtube"
 
-	"github.com/JohannesKaufmann/dom"
 	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
-	"golang.org/x/net/html"
 )
 
-func (pc *postConverter) renderYoutubeEmbedded(ctx converter.Context, w converter.Writer, node *html.Node) converter.RenderStatus {
-	// Get the src attribute
-	src := dom.GetAttributeOr(node, "src", "")
-	if src == "" {
-		return converter.RenderTryNext
+const unmatchedVideosSection = "Unmatched YouTube videos"
+
+// reportUnmatchedVideos reports the YouTube video files without metadata,
+// and the metadata rows without video file, in the report of the first blog only
+func (bc *blogConverter) reportUnmatchedVideos() {
+	if bc.data.YouTube == nil || !bc.isFirstBlog() {
+		return
+	}
+	files, videos := bc.data.YouTube.Unmatched()
+	for _, f := range files {
+		bc.report.add(unmatchedVideosSection, path.Base(f), "video file without metadata")
 	}
-	// Check if the src is a YouTube embed
-	if !strings.Contains(src, "youtube.com/embed/") {
-		return converter.RenderTryNext
+	for _, v := range videos {
+		bc.report.add(unmatchedVideosSection, v.Title, fmt.Sprintf("video %s without file", v.ID))
 	}
+}
 
-	// get the ID of the video
-	//  src="https://www.youtube.com/embed/VIDEOID?feature=player_embedded"
+// youTubeLink is the video or the playlist referenced by a YouTube URL
+type youTubeLink struct {
+	video string // video ID
+	list  string // playlist ID
+}
 
-	u, err := url.Parse(src)
+// parseYouTube recognizes the YouTube URLs found in posts:
+//
+//	https://www.youtube.com/embed/VIDEOID?feature=player_embedded
+//	https://www.youtube.com/embed/videoseries?list=PLAYLISTID
+//	https://www.youtube.com/watch?v=VIDEOID&list=PLAYLISTID
+//	https://www.youtube.com/playlist?list=PLAYLISTID
+//	https://youtu.be/VIDEOID
+//	http://www.youtube.com/v/VIDEOID&hl=fr&fs=1 (flash player)
+//	http://www.youtube.com/p/PLAYLISTID (flash playlist player)
+func parseYouTube(link string) (youTubeLink, bool) {
+	u, err := url.Parse(strings.TrimSpace(link))
 	if err != nil {
-		pc.log(w, ERROR, fmt.Sprintf("cannot parse video URL %s", src))
-		return converter.RenderTryNext
+		return youTubeLink{}, false
+	}
+	host := strings.TrimPrefix(strings.ToLower(u.Hostname()), "www.")
+	host = strings.TrimPrefix(host, "m.")
+	q := u.Query()
+	yl := youTubeLink{list: q.Get("list")}
+
+	// the flash players put their parameters after a & in the path
+	parts := strings.Split(strings.Trim(strings.SplitN(u.Path, "&", 2)[0], "/"), "/")
+	switch host {
+	case "youtu.be":
+		yl.video = parts[0]
+	case "youtube.com", "youtube-nocookie.com":
+		switch parts[0] {
+		case "embed", "v", "shorts", "live", "e":
+			if len(parts) > 1 && parts[1] != "videoseries" {
+				yl.video = parts[1]
+			}
+		case "watch":
+			yl.video = q.Get("v")
+		case "p":
+			if len(parts) > 1 {
+				yl.list = oldPlaylistID(parts[1])
+			}
+		case "view_play_list":
+			yl.list = oldPlaylistID(q.Get("p"))
+		case "playlist":
+		default:
+			return youTubeLink{}, false
+		}
+	default:
+		return youTubeLink{}, false
+	}
+	if yl.video == "" && yl.list == "" {
+		return youTubeLink{}, false
+	}
+	return yl, true
+}
+
+// oldPlaylistID adds the prefix missing from the playlist IDs of the flash players
+func oldPlaylistID(id string) string {
+	if id == "" || strings.HasPrefix(id, "PL") {
+		return id
+	}
+	return "PL" + id
+}
+
+// renderYouTube renders the YouTube video or playlist of the link.
+// It returns false when the link isn't a YouTube one.
+func (pc *postConverter) renderYouTube(ctx context.Context, w converter.Writer, link string) bool {
+	yl, ok := parseYouTube(link)
+	if !ok {
+		return false
+	}
+	if yl.video != "" {
+		pc.renderYouTubeVideo(ctx, w, yl.video, link)
+		return true
 	}
-	id := path.Base(u.Path)
+	pc.renderYouTubePlaylist(ctx, w, yl.list, link)
+	return true
+}
 
-	// search the video in takeout data
-	v := pc.data.YouTube.SearchByID(ctx, id)
+// renderYouTubeVideo renders the video file found in the takeout,
+// or the YouTube player when the video isn't in the takeout
+func (pc *postConverter) renderYouTubeVideo(ctx context.Context, w converter.Writer, id string, src string) {
+	var v *youtube.Video
+	if pc.data.YouTube != nil {
+		v = pc.data.YouTube.SearchByID(ctx, id)
+	}
 	if v == nil {
 		pc.log(w, MISSING_ORIGINAL, fmt.Sprintf("cannot find video %s in the given takeout data", id))
-		return converter.RenderTryNext
-
+		v = &youtube.Video{ID: id}
 	}
 
+	caption := strings.Join(strings.Fields(v.Description), " ")
+	if caption == "" {
+		caption = v.Title
+	}
 	pc.renderVideo(ctx, w, video{
 		Resource: v.Resource,
 		YTId:     id,
 		Source:   src,
 		Name:     filename.Sanitize(path.Base(v.FileName)),
-		Caption:  v.Title,
+		Caption:  caption,
+		Title:    v.Title,
+		Duration: v.Duration,
+		Poster:   v.Thumbnail,
 	})
-	return converter.RenderSuccess
+}
+
+// renderYouTubePlaylist renders the videos of the playlist found in the takeout,
+// or the YouTube playlist player when none of them is in the takeout
+func (pc *postConverter) renderYouTubePlaylist(ctx context.Context, w converter.Writer, id string, src string) {
+	var p *youtube.Playlist
+	if pc.data.YouTube != nil {
+		p = pc.data.YouTube.SearchPlaylist(ctx, id)
+	}
+	if p != nil && slices.ContainsFunc(p.VideoIDs, func(id string) bool {
+		v := pc.data.YouTube.SearchByID(ctx, id)
+		return v != nil && v.Resource != nil
+	}) {
+		for _, id := range p.VideoIDs {
+			w.WriteString("\n\n")
+			pc.renderYouTubeVideo(ctx, w, id, src)
+		}
+		w.WriteString("\n\n")
+		return
+	}
+	pc.log(w, MISSING_ORIGINAL, fmt.Sprintf("cannot find the videos of the playlist %s in the given takeout data", id))
+	w.WriteString("{{< media/ytplaylist " + strconv.Quote(id) + " >}}")
 }
@@ -0,0 +1,148 @@
+package convert
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"bloggerout/internal/takeout"
+	"bloggerout/internal/takeout/resources"
+	"bloggerout/internal/takeout/youtube"
+
+	"github.com/JohannesKaufmann/html-to-markdown/v2/converter"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/base"
+	"github.com/JohannesKaufmann/html-to-markdown/v2/plugin/commonmark"
+	"github.com/simulot/TakeoutLocalization/go/localization"
+)
+
+func TestParseYouTube(t *testing.T) {
+	testCases := []struct {
+		link  string
+		video string
+		list  string
+		ok    bool
+	}{
+		{"https://www.youtube.com/embed/abc123?feature=player_embedded", "abc123", "", true},
+		{"https://www.youtube-nocookie.com/embed/abc123", "abc123", "", true},
+		{"https://www.youtube.com/embed/videoseries?list=PL123", "", "PL123", true},
+		{"https://www.youtube.com/watch?v=abc123&list=PL123", "abc123", "PL123", true},
+		{"https://m.youtube.com/watch?v=abc123", "abc123", "", true},
+		{"https://www.youtube.com/playlist?list=PL123", "", "PL123", true},
+		{"https://youtu.be/abc123?t=10", "abc123", "", true},
+		{"http://www.youtube.com/v/abc123&hl=fr&fs=1", "abc123", "", true},
+		{"http://www.youtube.com/p/0BF9DEB9&hl=fr", "", "PL0BF9DEB9", true},
+		{"http://www.youtube.com/view_play_list?p=0BF9DEB9", "", "PL0BF9DEB9", true},
+		{"https://www.youtube.com/watch", "", "", false},
+		{"https://www.youtube.com/user/someone", "", "", false},
+		{"https://vimeo.com/123", "", "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.link, func(t *testing.T) {
+			yl, ok := parseYouTube(tc.link)
+			if ok != tc.ok || yl.video != tc.video || yl.list != tc.list {
+				t.Errorf("parseYouTube(%q) = %+v, %v; want video %q, list %q, %v", tc.link, yl, ok, tc.video, tc.list, tc.ok)
+			}
+		})
+	}
+}
+
+func TestYouTubeHandlers(t *testing.T) {
+	vfs := fstest.MapFS{
+		"YouTube/channels/channel.csv": &fstest.MapFile{Data: []byte("Channel ID,Channel Title (Original)\nch,Family\n")},
+		"YouTube/video metadata/videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Channel ID,Video Title (Original)
+id1,ch,Holidays
+id2,ch,Birthday
+`)},
+		"YouTube/videos/Holidays.mp4": &fstest.MapFile{Data: []byte("video")},
+		"YouTube/videos/Birthday.mp4": &fstest.MapFile{Data: []byte("video")},
+		"YouTube/playlists/playlists.csv": &fstest.MapFile{Data: []byte(`Playlist ID,Playlist Title (Original)
+PL123,Summer
+PL456,Elsewhere
+`)},
+		"YouTube/playlists/Summer-videos.csv":    &fstest.MapFile{Data: []byte("Video ID\nid2\nidX\nid1\n")},
+		"YouTube/playlists/Elsewhere-videos.csv": &fstest.MapFile{Data: []byte("Video ID\nidY\n")},
+	}
+	rs := resources.New()
+	yt, err := youtube.New(vfs, rs, &localization.Products{}).Scan(context.Background(), "YouTube", "YouTube and YouTube Music")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name string
+		html string
+		want []string
+	}{
+		{"iframe", `<iframe src="https://www.youtube.com/embed/id1?feature=player_embedded"></iframe>`, []string{`src="Holidays.mp4"`}},
+		{"missing", `<iframe src="https://www.youtube.com/embed/idZ"></iframe>`, []string{`{{< media/ytembed idZ >}}`}},
+		{"flash", `<object width="425" height="344"><param name="movie" value="http://www.youtube.com/v/id2&hl=fr&fs=1"></param><embed src="http://www.youtube.com/v/id2&hl=fr&fs=1" type="application/x-shockwave-flash"></embed></object>`, []string{`src="Birthday.mp4"`}},
+		{"flash_embed", `<embed src="http://www.youtube.com/v/id2&hl=fr&fs=1" type="application/x-shockwave-flash"></embed>`, []string{`src="Birthday.mp4"`}},
+		{"playlist", `<iframe src="https://www.youtube.com/embed/videoseries?list=PL123"></iframe>`, []string{`src="Birthday.mp4"`, `{{< media/ytembed idX >}}`, `src="Holidays.mp4"`}},
+		{"playlist_elsewhere", `<iframe src="https://www.youtube.com/embed/videoseries?list=PL456"></iframe>`, []string{`{{< media/ytplaylist "PL456" >}}`}},
+		{"unknown_playlist", `<iframe src="https://www.youtube.com/embed/videoseries?list=PL789"></iframe>`, []string{`{{< media/ytplaylist "PL789" >}}`}},
+		{"youtu.be", `<p><a href="https://youtu.be/id1">https://youtu.be/id1</a></p>`, []string{`src="Holidays.mp4"`}},
+		{"thumbnail", `<a href="https://www.youtube.com/watch?v=id2"><img src="https://i.ytimg.com/vi/id2/0.jpg"></a>`, []string{`src="Birthday.mp4"`}},
+		{"text_link", `<p>See <a href="https://www.youtube.com/watch?v=id1">my video</a></p>`, []string{`[my video](https://www.youtube.com/watch?v=id1)`}},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			pfs, err := os.OpenRoot(t.TempDir())
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer pfs.Close()
+			pc := &postConverter{
+				blogConverter: &blogConverter{Convert: &Convert{}, data: &takeout.Takeout{Resources: rs, YouTube: yt}, report: newConversionReport()},
+				errors:        map[string]int{},
+				resources:     map[string]*resource{},
+				pfs:           pfs,
+			}
+			mdc := converter.NewConverter(converter.WithPlugins(base.NewBasePlugin(), commonmark.NewCommonmarkPlugin()))
+			mdc.Register.RendererFor("iframe", converter.TagTypeBlock, pc.iFrameHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("object", converter.TagTypeBlock, pc.videoObjectHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("embed", converter.TagTypeBlock, pc.embedHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("a", converter.TagTypeInline, pc.anchorImgHandler, converter.PriorityEarly)
+			mdc.Register.RendererFor("a", converter.TagTypeInline, pc.anchorHandler, converter.PriorityEarly+10)
+
+			got, err := mdc.ConvertString(postBody(tc.html))
+			if err != nil {
+				t.Fatal(err)
+			}
+			last := -1
+			for _, want := range tc.want {
+				i := strings.Index(got, want)
+				if i <= last {
+					t.Errorf("%s not rendered in order: %s", want, got)
+				}
+				last = i
+			}
+		})
+	}
+}
+
+func TestReportUnmatchedVideos(t *testing.T) {
+	vfs := fstest.MapFS{
+		"YouTube/channels/channel.csv": &fstest.MapFile{Data: []byte("Channel ID,Channel Title (Original)\nch1,Family\n")},
+		"YouTube/video metadata/videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Video Description (Original),Channel ID,Video Title (Original),Privacy,Video Create Timestamp,Video Publish Timestamp
+id1,,ch1,Lost,Public,2020-02-01T10:00:00+00:00,
+`)},
+		"YouTube/videos/Unknown.mp4": &fstest.MapFile{Data: []byte("video")},
+	}
+	rs := resources.New()
+	yt, err := youtube.New(vfs, rs, &localization.Products{}).Scan(context.Background(), "YouTube", "YouTube and YouTube Music")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the takeout-wide list is reported once
+	c := &Convert{blogs: []string{"Other", "Blog"}}
+	for blog, want := range map[string]int{"Blog": 2, "Other": 0} {
+		bc := &blogConverter{Convert: c, blog: blog, data: &takeout.Takeout{Resources: rs, YouTube: yt}, report: newConversionReport()}
+		bc.reportUnmatchedVideos()
+		if got := len(bc.report.sections[unmatchedVideosSection]); got != want {
+			t.Errorf("%s: %d unmatched videos reported; want %d", blog, got, want)
+		}
+	}
+}
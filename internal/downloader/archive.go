@@ -0,0 +1,96 @@
+package downloader
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// DefaultArchiveURL is the base URL of the Internet Archive's availability API
+const DefaultArchiveURL = "https://archive.org"
+
+// ErrNotArchived is returned when the Internet Archive has no snapshot of the URL
+var ErrNotArchived = errors.New("no snapshot in the Internet Archive")
+
+// availability is the response of the Wayback Machine availability API
+// https://archive.org/help/wayback_api.php
+type availability struct {
+	ArchivedSnapshots struct {
+		Closest *struct {
+			Available bool   `json:"available"`
+			URL       string `json:"url"`
+			Timestamp string `json:"timestamp"`
+			Status    string `json:"status"`
+		} `json:"closest"`
+	} `json:"archived_snapshots"`
+}
+
+// Snapshot returns the URL of the archived copy of the link closest to the date.
+func (d *Downloader) Snapshot(ctx context.Context, link string, date time.Time) (string, error) {
+	base := d.options.ArchiveURL
+	if base == "" {
+		base = DefaultArchiveURL
+	}
+	q := url.Values{}
+	q.Set("url", link)
+	if !date.IsZero() {
+		q.Set("timestamp", date.UTC().Format("20060102150405"))
+	}
+	api := strings.TrimSuffix(base, "/") + "/wayback/available?" + q.Encode()
+
+	body, _, err := d.fetch(ctx, api)
+	if err != nil {
+		return "", fmt.Errorf("can't query the Internet Archive: %w", err)
+	}
+	defer body.Close()
+	var a availability
+	err = json.NewDecoder(io.LimitReader(body, 1<<20)).Decode(&a)
+	if err != nil {
+		return "", fmt.Errorf("can't read the Internet Archive response: %w", err)
+	}
+	c := a.ArchivedSnapshots.Closest
+	if c == nil || !c.Available || c.URL == "" || (c.Status != "" && c.Status != "200") {
+		return "", fmt.Errorf("%s: %w", link, ErrNotArchived)
+	}
+	return c.URL, nil
+}
+
+// snapshotPath matches the path of a Wayback Machine snapshot: /web/20100101000000/http://...
+var snapshotPath = regexp.MustCompile(`^(/web/\d+)(?:[a-z]{2}_)?/`)
+
+// rawSnapshot gives the URL of the snapshot's original content, without the Wayback Machine banner
+func rawSnapshot(snapshot string) string {
+	u, err := url.Parse(snapshot)
+	if err != nil {
+		return snapshot
+	}
+	m := snapshotPath.FindStringSubmatchIndex(u.Path)
+	if m == nil {
+		return snapshot
+	}
+	raw := u.Scheme + "://" + u.Host + u.Path[:m[3]] + "id_/" + u.Path[m[1]:]
+	if u.RawQuery != "" {
+		raw += "?" + u.RawQuery
+	}
+	return raw
+}
+
+// DownloadArchived downloads the archived copy of the image closest to the date,
+// and returns the snapshot's URL
+func (d *Downloader) DownloadArchived(ctx context.Context, link string, date time.Time, w io.Writer) (string, error) {
+	snapshot, err := d.Snapshot(ctx, link, date)
+	if err != nil {
+		return "", err
+	}
+	err = d.DownloadFile(ctx, rawSnapshot(snapshot), w)
+	if err != nil {
+		return "", err
+	}
+	return snapshot, nil
+}
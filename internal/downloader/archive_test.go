@@ -0,0 +1,74 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRawSnapshot(t *testing.T) {
+	testCases := []struct {
+		snapshot string
+		want     string
+	}{
+		{"http://web.archive.org/web/20100101000000/http://example.com/img.jpg", "http://web.archive.org/web/20100101000000id_/http://example.com/img.jpg"},
+		{"http://web.archive.org/web/20100101000000im_/http://example.com/img.jpg?s=1", "http://web.archive.org/web/20100101000000id_/http://example.com/img.jpg?s=1"},
+		{"http://example.com/other", "http://example.com/other"},
+	}
+	for _, tc := range testCases {
+		if got := rawSnapshot(tc.snapshot); got != tc.want {
+			t.Errorf("rawSnapshot(%q) = %q; want %q", tc.snapshot, got, tc.want)
+		}
+	}
+}
+
+func TestDownloadArchived(t *testing.T) {
+	var timestamp string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/wayback/available":
+			timestamp = r.URL.Query().Get("timestamp")
+			if r.URL.Query().Get("url") != "http://gone.example/img.gif" {
+				fmt.Fprint(w, `{"archived_snapshots": {}}`)
+				return
+			}
+			fmt.Fprintf(w, `{"archived_snapshots": {"closest": {"available": true, "url": "%s/web/20100101000000/http://gone.example/img.gif", "timestamp": "20100101000000", "status": "200"}}}`, srv.URL)
+		case "/web/20100101000000id_/http://gone.example/img.gif":
+			w.Header().Set("Content-Type", "image/gif")
+			w.Write(gif)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.ArchiveURL = srv.URL
+	d := NewDownloader(o)
+
+	var buf bytes.Buffer
+	snapshot, err := d.DownloadArchived(context.Background(), "http://gone.example/img.gif", time.Date(2009, 12, 24, 10, 0, 0, 0, time.UTC), &buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot != srv.URL+"/web/20100101000000/http://gone.example/img.gif" {
+		t.Errorf("unexpected snapshot %q", snapshot)
+	}
+	if timestamp != "20091224100000" {
+		t.Errorf("unexpected timestamp %q", timestamp)
+	}
+	if !bytes.Equal(buf.Bytes(), gif) {
+		t.Errorf("unexpected content %q", buf.Bytes())
+	}
+
+	_, err = d.DownloadArchived(context.Background(), "http://gone.example/other.gif", time.Time{}, &bytes.Buffer{})
+	if !errors.Is(err, ErrNotArchived) {
+		t.Errorf("DownloadArchived() error = %v; want ErrNotArchived", err)
+	}
+}
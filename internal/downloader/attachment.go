@@ -0,0 +1,120 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// ErrNotAttachment is returned when the link's content isn't a document or an audio file
+var ErrNotAttachment = errors.New("not a document or an audio file")
+
+// attachmentTypes gives the extension of the document types kept with the posts
+var attachmentTypes = map[string]string{
+	"application/pdf":    ".pdf",
+	"application/msword": ".doc",
+	"application/vnd.openxmlformats-officedocument.wordprocessingml.document": ".docx",
+	"application/vnd.ms-excel": ".xls",
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet":         ".xlsx",
+	"application/vnd.ms-powerpoint":                                             ".ppt",
+	"application/vnd.openxmlformats-officedocument.presentationml.presentation": ".pptx",
+	"application/vnd.oasis.opendocument.text":                                   ".odt",
+	"application/vnd.oasis.opendocument.spreadsheet":                            ".ods",
+	"application/vnd.oasis.opendocument.presentation":                           ".odp",
+	"application/rtf": ".rtf",
+	"audio/mpeg":      ".mp3",
+	"audio/mp4":       ".m4a",
+	"audio/ogg":       ".ogg",
+	"audio/wav":       ".wav",
+	"audio/x-wav":     ".wav",
+	"audio/flac":      ".flac",
+}
+
+// Attachment describes a document or an audio file linked by a post
+type Attachment struct {
+	ContentType string // media type, without parameters
+	Name        string // file name given by the server, else the last element of the URL path
+}
+
+// IsAttachmentType tells if the media type is a document or an audio file
+func IsAttachmentType(mediaType string) bool {
+	_, ok := attachmentTypes[mediaType]
+	return ok || strings.HasPrefix(mediaType, "audio/")
+}
+
+// DownloadAttachment writes the document or audio file at the URL when its size doesn't exceed maxSize bytes,
+// nor the MaxSize option applied to all downloads.
+// The other contents give ErrNotAttachment, without being downloaded when the server answers to HEAD requests.
+func (d *Downloader) DownloadAttachment(ctx context.Context, link string, maxSize int64, w io.Writer) (Attachment, error) {
+	var head http.Header // the HEAD response gives the file name, when any
+	if !d.options.Offline {
+		resp, err := d.do(ctx, http.MethodHead, link, nil)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				head = resp.Header
+				a := attachment(link, head)
+				if !IsAttachmentType(a.ContentType) {
+					return a, ErrNotAttachment
+				}
+				if maxSize > 0 && resp.ContentLength > maxSize {
+					return a, reject(link, "size of %d bytes larger than the limit of %d bytes", resp.ContentLength, maxSize)
+				}
+			}
+		}
+	}
+
+	rc, contentType, err := d.fetch(ctx, link)
+	if err != nil {
+		return Attachment{}, err
+	}
+	defer rc.Close()
+	h := http.Header{"Content-Type": {contentType}}
+	if head != nil {
+		h.Set("Content-Disposition", head.Get("Content-Disposition"))
+	}
+	a := attachment(link, h)
+	if !IsAttachmentType(a.ContentType) {
+		return a, ErrNotAttachment
+	}
+	var r io.Reader = rc
+	if maxSize > 0 {
+		r = io.LimitReader(rc, maxSize+1)
+	}
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return a, err
+	}
+	if maxSize > 0 && n > maxSize {
+		return a, reject(link, "larger than the limit of %d bytes", maxSize)
+	}
+	return a, nil
+}
+
+// attachment describes the file from the response headers
+func attachment(link string, h http.Header) Attachment {
+	a := Attachment{}
+	a.ContentType, _, _ = mime.ParseMediaType(h.Get("Content-Type"))
+	if _, params, err := mime.ParseMediaType(h.Get("Content-Disposition")); err == nil {
+		a.Name = path.Base(params["filename"])
+	}
+	if a.Name == "" || a.Name == "." || a.Name == "/" {
+		a.Name = "attachment"
+		if u, err := url.Parse(link); err == nil && path.Base(u.Path) != "/" && path.Base(u.Path) != "." {
+			a.Name = path.Base(u.Path)
+		}
+	}
+	if path.Ext(a.Name) == "" {
+		if ext, ok := attachmentTypes[a.ContentType]; ok {
+			a.Name += ext
+		} else if exts, _ := mime.ExtensionsByType(a.ContentType); len(exts) > 0 {
+			a.Name += exts[0]
+		}
+	}
+	return a
+}
@@ -0,0 +1,102 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDownloadAttachment(t *testing.T) {
+	pdf := []byte("%PDF-1.4 content")
+	var gets atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			gets.Add(1)
+		}
+		switch r.URL.Path {
+		case "/file.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(pdf)
+		case "/attachment":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", `attachment; filename="Club news.pdf"`)
+			w.Write(pdf)
+		case "/song":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(pdf)
+		case "/big.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(bytes.Repeat(pdf, 10))
+		case "/chunked.mp3":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+				return
+			}
+			w.Header().Set("Content-Type", "audio/mpeg")
+			w.Write(pdf)
+			w.(http.Flusher).Flush()
+			w.Write(bytes.Repeat(pdf, 10))
+		case "/page.pdf":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			fmt.Fprint(w, "<html></html>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	d := NewDownloader(testOptions())
+	testCases := []struct {
+		path        string
+		contentType string
+		name        string
+		gets        int32
+		reason      string // rejection reason
+		notAttached bool
+	}{
+		{"/file.pdf", "application/pdf", "file.pdf", 1, "", false},
+		{"/attachment", "application/pdf", "Club news.pdf", 1, "", false},
+		{"/song", "audio/mpeg", "song.mp3", 1, "", false},
+		{"/big.pdf", "application/pdf", "big.pdf", 0, "size of 160 bytes larger than the limit", false},
+		{"/chunked.mp3", "audio/mpeg", "chunked.mp3", 1, "larger than the limit", false},
+		{"/page.pdf", "text/html", "page.pdf", 0, "", true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			gets.Store(0)
+			var buf bytes.Buffer
+			a, err := d.DownloadAttachment(context.Background(), srv.URL+tc.path, 100, &buf)
+			var rejected *RejectedError
+			switch {
+			case tc.notAttached:
+				if !errors.Is(err, ErrNotAttachment) {
+					t.Errorf("DownloadAttachment() error = %v; want ErrNotAttachment", err)
+				}
+			case tc.reason != "":
+				if !errors.As(err, &rejected) || !strings.HasPrefix(rejected.Reason, tc.reason) {
+					t.Errorf("DownloadAttachment() error = %v; want rejected with %q", err, tc.reason)
+				}
+			case err != nil:
+				t.Fatalf("DownloadAttachment() error = %v", err)
+			case !bytes.Equal(buf.Bytes(), pdf):
+				t.Errorf("unexpected content %q", buf.Bytes())
+			}
+			if a.ContentType != tc.contentType || a.Name != tc.name {
+				t.Errorf("DownloadAttachment() = %+v; want %s, %s", a, tc.contentType, tc.name)
+			}
+			if gets.Load() != tc.gets {
+				t.Errorf("%d GET requests; want %d", gets.Load(), tc.gets)
+			}
+		})
+	}
+}
@@ -0,0 +1,114 @@
+package downloader
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrOffline is returned in offline mode for the contents missing from the cache
+var ErrOffline = errors.New("not in the download cache (offline mode)")
+
+// cache keeps the downloaded contents on disk, keyed by URL.
+// Each entry is made of a body file and a JSON file with the validators of the response.
+type cache struct {
+	dir string
+}
+
+// cacheEntry holds what is needed to revalidate and serve a cached content
+type cacheEntry struct {
+	URL          string
+	ContentType  string
+	ETag         string    `json:",omitempty"`
+	LastModified string    `json:",omitempty"`
+	Fetched      time.Time // time of the last download or revalidation
+}
+
+func newCache(dir string) (*cache, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, err
+	}
+	return &cache{dir: dir}, nil
+}
+
+func (c *cache) key(url string) string {
+	h := sha256.Sum256([]byte(url))
+	return filepath.Join(c.dir, hex.EncodeToString(h[:]))
+}
+
+// get returns the entry of the URL, nil when not cached
+func (c *cache) get(url string) *cacheEntry {
+	b, err := os.ReadFile(c.key(url) + ".json")
+	if err != nil {
+		return nil
+	}
+	e := &cacheEntry{}
+	if json.Unmarshal(b, e) != nil || e.URL != url {
+		return nil
+	}
+	if _, err := os.Stat(c.key(url) + ".body"); err != nil {
+		return nil
+	}
+	return e
+}
+
+// open returns the cached body of the URL
+func (c *cache) open(url string) (io.ReadCloser, error) {
+	return os.Open(c.key(url) + ".body")
+}
+
+// put stores the body of a successful response and returns the cache entry.
+// The body is written in a temporary file, renamed once complete.
+func (c *cache) put(url string, resp *http.Response) (*cacheEntry, error) {
+	key := c.key(url)
+	tmp, err := os.CreateTemp(c.dir, filepath.Base(key)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, resp.Body)
+	if err1 := tmp.Close(); err == nil {
+		err = err1
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = os.Rename(tmp.Name(), key+".body")
+	if err != nil {
+		return nil, err
+	}
+	e := &cacheEntry{
+		URL:          url,
+		ContentType:  resp.Header.Get("Content-Type"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+	}
+	return e, c.touch(e)
+}
+
+// touch records the revalidation of the entry
+func (c *cache) touch(e *cacheEntry) error {
+	e.Fetched = time.Now()
+	b, err := json.MarshalIndent(e, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(c.key(e.URL)+".json", b, 0o644)
+}
+
+// setValidators adds the conditional headers of the cached entry to the request
+func (e *cacheEntry) setValidators(req *http.Request) {
+	if e.ETag != "" {
+		req.Header.Set("If-None-Match", e.ETag)
+	}
+	if e.LastModified != "" {
+		req.Header.Set("If-Modified-Since", e.LastModified)
+	}
+}
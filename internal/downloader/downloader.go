@@ -1,78 +1,233 @@
 package downloader
 
 import (
+	"bytes"
+	"cmp"
 	"context"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
+	neturl "net/url"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/net/html"
 )
 
+// DefaultUserAgent identifies the tool to the web servers
+const DefaultUserAgent = "Mozilla/5.0 (compatible; bloggerout)"
+
+// Options tunes the downloader's politeness and resilience
+type Options struct {
+	Timeout         time.Duration // timeout of a request, body included
+	Retries         int           // attempts made after the first one on network errors, 429 and 5xx statuses
+	Backoff         time.Duration // delay before the first retry, doubled at each attempt
+	MaxBackoff      time.Duration // maximum delay between two attempts, Retry-After included
+	HostConcurrency int           // simultaneous requests per host, 0 for no limit
+	HostRate        float64       // requests per second per host, 0 for no limit
+	UserAgent       string        // User-Agent header of the requests
+	CacheDir        string        // directory of the download cache, empty for no cache
+	Offline         bool          // use only the cached contents
+	ArchiveURL      string        // base URL of the Internet Archive's availability API
+	MaxSize         int64         // size limit of a download in bytes, 0 for no limit
+	Placeholders    []string      // placeholder images to reject besides the known ones: SHA-256 in hexadecimal, or "400x300 png"
+}
+
+// DefaultOptions gives settings accepted by the Google servers
+func DefaultOptions() Options {
+	return Options{
+		Timeout:         30 * time.Second,
+		Retries:         3,
+		Backoff:         time.Second,
+		MaxBackoff:      time.Minute,
+		HostConcurrency: 4,
+		HostRate:        10,
+		UserAgent:       DefaultUserAgent,
+		ArchiveURL:      DefaultArchiveURL,
+		MaxSize:         DefaultMaxSize,
+	}
+}
+
 type Downloader struct {
-	client http.Client
+	client  http.Client
+	checker http.Client // doesn't follow the redirects
+	options Options
+	cache   *cache // nil when disabled
+
+	placeholders map[string]bool // SHA-256 and dimensions of the placeholder images
+
+	mu    sync.Mutex
+	hosts map[string]*hostLimiter // by host name
 }
 
-func NewDownloader() *Downloader {
+func NewDownloader(options Options) *Downloader {
 	// Safe HTTP client settings
 	client := http.Client{
-		Timeout: 30 * time.Second,
+		Timeout:       options.Timeout,
+		CheckRedirect: checkRedirect,
 		Transport: &http.Transport{
 			MaxIdleConns:       10,
 			IdleConnTimeout:    30 * time.Second,
 			DisableCompression: false,
 		},
 	}
-	return &Downloader{
-		client: client,
+	if options.UserAgent == "" {
+		options.UserAgent = DefaultUserAgent
+	}
+	checker := client
+	checker.CheckRedirect = func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+	d := &Downloader{
+		client:  client,
+		checker: checker,
+		options: options,
+		hosts:   make(map[string]*hostLimiter),
+
+		placeholders: placeholderSet(options.Placeholders),
+	}
+	if options.CacheDir != "" {
+		c, err := newCache(options.CacheDir)
+		if err != nil {
+			slog.Warn("download cache disabled", "dir", options.CacheDir, "error", err)
+		}
+		d.cache = c
 	}
+	return d
 }
 
+// fetch gets the content of the URL, from the cache when it's still valid.
+// The cached content is served when the server can't be reached.
+func (d *Downloader) fetch(ctx context.Context, url string) (io.ReadCloser, string, error) {
+	var e *cacheEntry
+	if d.cache != nil {
+		e = d.cache.get(url)
+	}
+	if d.options.Offline {
+		if e == nil {
+			return nil, "", fmt.Errorf("%s: %w", url, ErrOffline)
+		}
+		body, err := d.cache.open(url)
+		return body, e.ContentType, err
+	}
+
+	resp, err := d.do(ctx, http.MethodGet, url, func(req *http.Request) {
+		if e != nil {
+			e.setValidators(req)
+		}
+	})
+	if err != nil {
+		if e != nil && ctx.Err() == nil {
+			slog.Warn("server unreachable, cached content used", "url", url, "error", err)
+			body, err := d.cache.open(url)
+			return body, e.ContentType, err
+		}
+		return nil, "", err
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		err = d.limitSize(url, resp)
+		if err != nil {
+			resp.Body.Close()
+			return nil, "", err
+		}
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified && e != nil:
+		resp.Body.Close()
+		err = d.cache.touch(e)
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := d.cache.open(url)
+		return body, e.ContentType, err
+	case resp.StatusCode != http.StatusOK:
+		resp.Body.Close()
+		return nil, "", fmt.Errorf("failed to download file: %s, status: %s", url, resp.Status)
+	case d.cache != nil:
+		e, err = d.cache.put(url, resp)
+		resp.Body.Close()
+		if err != nil {
+			return nil, "", err
+		}
+		body, err := d.cache.open(url)
+		return body, e.ContentType, err
+	}
+	return resp.Body, resp.Header.Get("Content-Type"), nil
+}
+
+// DownloadFile writes the image at the URL, or the image shown by the HTML page at the URL.
+// The contents other than a valid image are rejected with a RejectedError.
 func (d *Downloader) DownloadFile(ctx context.Context, url string, w io.Writer) error {
-	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	return d.download(ctx, url, w, true)
+}
+
+// download gets the image, following the HTML page when page is true
+func (d *Downloader) download(ctx context.Context, url string, w io.Writer, page bool) error {
+	rc, contentType, err := d.fetch(ctx, url)
 	if err != nil {
 		return err
 	}
-	resp, err := d.client.Do(req)
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("failed to download file: %s, status: %s", url, resp.Status)
+	// servers often give a generic type to images, the content decides
+	if contentType == "" || strings.HasPrefix(contentType, "application/octet-stream") {
+		contentType = http.DetectContentType(content)
 	}
 
-	if strings.Contains(resp.Header.Get("Content-Type"), "image/") {
-		_, err = io.Copy(w, resp.Body)
+	switch {
+	case strings.Contains(contentType, "image/"):
+		err = d.validateImage(url, content)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(content)
 		return err
-	}
-
-	if strings.Contains(resp.Header.Get("Content-Type"), "text/html") {
-		url, err := getImgSrc(resp.Body)
+	case strings.Contains(contentType, "text/html"):
+		if !page {
+			return reject(url, "HTML page instead of an image")
+		}
+		src, err := pageImage(url, bytes.NewReader(content))
 		if err != nil {
 			return err
 		}
-		return d.DownloadFile(ctx, url, w)
+		rc.Close() // frees the host's slot for the image request
+		return d.download(ctx, src, w, false)
 	}
-	return fmt.Errorf("unsupported content type: %s", resp.Header.Get("Content-Type"))
+	return reject(url, "unsupported content type %s", contentType)
 }
 
-func getImgSrc(r io.Reader) (string, error) {
+// pageImage returns the image shown by the page: the one given to the social networks, else the first one.
+// Login pages are rejected.
+func pageImage(page string, r io.Reader) (string, error) {
 	doc, err := html.Parse(r)
 	if err != nil {
 		return "", err
 	}
-	var url string
+	var ogImage, img string
+	login := false
 	var f func(*html.Node)
 	f = func(n *html.Node) {
-		if n.Type == html.ElementNode && n.Data == "img" {
-			for _, a := range n.Attr {
-				if a.Key == "src" {
-					url = a.Val
-					return
+		if n.Type == html.ElementNode {
+			switch n.Data {
+			case "meta":
+				if attr(n, "property") == "og:image" && ogImage == "" {
+					ogImage = attr(n, "content")
+				}
+			case "img":
+				if img == "" {
+					img = attr(n, "src")
+				}
+			case "input":
+				if strings.EqualFold(attr(n, "type"), "password") {
+					login = true
 				}
 			}
 		}
@@ -81,23 +236,29 @@ func getImgSrc(r io.Reader) (string, error) {
 		}
 	}
 	f(doc)
-	if url == "" {
-		return "", fmt.Errorf("no img src found")
+	if login {
+		return "", reject(page, "login page instead of an image")
 	}
-	return url, nil
-}
-
-func (d *Downloader) CheckLink(ctx context.Context, url string, yeld func(url string, status int, err error)) {
-	req, err := http.NewRequestWithContext(ctx, "HEAD", url, nil)
+	src := cmp.Or(ogImage, img)
+	if src == "" {
+		return "", reject(page, "no image in the HTML page")
+	}
+	base, err := neturl.Parse(page)
 	if err != nil {
-		yeld(url, 0, err)
-		return
+		return "", err
 	}
-	resp, err := d.client.Do(req)
+	u, err := base.Parse(src)
 	if err != nil {
-		yeld(url, 0, err)
-		return
+		return "", err
+	}
+	return u.String(), nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
 	}
-	defer resp.Body.Close()
-	yeld(url, resp.StatusCode, nil)
+	return ""
 }
@@ -0,0 +1,285 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+var gif = []byte("GIF89a\x02\x00\x02\x00\x00\x00\x00;")
+
+func testOptions() Options {
+	o := DefaultOptions()
+	o.Backoff = time.Millisecond
+	o.MaxBackoff = 10 * time.Millisecond
+	o.HostRate = 0
+	return o
+}
+
+func TestRetries(t *testing.T) {
+	testCases := []struct {
+		name     string
+		failures []int // statuses returned before the image
+		retries  int
+		wantErr  bool
+		attempts int32
+	}{
+		{"success", nil, 3, false, 1},
+		{"throttled", []int{http.StatusTooManyRequests, http.StatusServiceUnavailable}, 3, false, 3},
+		{"too_many_failures", []int{500, 502, 503, 504}, 3, true, 4},
+		{"not_found", []int{http.StatusNotFound}, 3, true, 1},
+		{"no_retry", []int{http.StatusServiceUnavailable}, 0, true, 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var attempts atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := int(attempts.Add(1))
+				if n <= len(tc.failures) {
+					w.Header().Set("Retry-After", "0")
+					w.WriteHeader(tc.failures[n-1])
+					return
+				}
+				w.Header().Set("Content-Type", "image/gif")
+				w.Write(gif)
+			}))
+			defer srv.Close()
+
+			o := testOptions()
+			o.Retries = tc.retries
+			var buf bytes.Buffer
+			err := NewDownloader(o).DownloadFile(context.Background(), srv.URL+"/image.gif", &buf)
+			if (err != nil) != tc.wantErr {
+				t.Errorf("DownloadFile() error = %v, want error %v", err, tc.wantErr)
+			}
+			if got := attempts.Load(); got != tc.attempts {
+				t.Errorf("attempts = %d, want %d", got, tc.attempts)
+			}
+			if !tc.wantErr && !bytes.Equal(buf.Bytes(), gif) {
+				t.Errorf("unexpected content %q", buf.Bytes())
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	now := time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		value string
+		want  time.Duration
+		ok    bool
+	}{
+		{"", 0, false},
+		{"120", 2 * time.Minute, true},
+		{"-1", 0, false},
+		{"Tue, 01 Jul 2025 12:00:30 GMT", 30 * time.Second, true},
+		{"Tue, 01 Jul 2025 11:00:00 GMT", 0, true},
+		{"soon", 0, false},
+	}
+	for _, tc := range testCases {
+		got, ok := retryAfter(tc.value, now)
+		if got != tc.want || ok != tc.ok {
+			t.Errorf("retryAfter(%q) = %v, %v; want %v, %v", tc.value, got, ok, tc.want, tc.ok)
+		}
+	}
+}
+
+func TestRetryAfterHonored(t *testing.T) {
+	var attempts atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if attempts.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.Write(gif)
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.MaxBackoff = 50 * time.Millisecond // caps the server's Retry-After
+	start := time.Now()
+	err := NewDownloader(o).DownloadFile(context.Background(), srv.URL, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d := time.Since(start); d < 50*time.Millisecond || d > 900*time.Millisecond {
+		t.Errorf("waited %v, want about 50ms", d)
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	var ua string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ua = r.Header.Get("User-Agent")
+		w.Write(gif)
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.UserAgent = "test-agent"
+	err := NewDownloader(o).DownloadFile(context.Background(), srv.URL, &bytes.Buffer{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ua != "test-agent" {
+		t.Errorf("User-Agent = %q, want test-agent", ua)
+	}
+}
+
+func TestHostConcurrency(t *testing.T) {
+	var running, maxRunning atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write(gif)
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.HostConcurrency = 2
+	d := NewDownloader(o)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := d.DownloadFile(context.Background(), srv.URL, &bytes.Buffer{}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if m := maxRunning.Load(); m > 2 {
+		t.Errorf("%d simultaneous requests, want at most 2", m)
+	}
+}
+
+func TestHostRate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gif)
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.HostRate = 50 // a request every 20ms
+	d := NewDownloader(o)
+	start := time.Now()
+	for range 5 {
+		if err := d.DownloadFile(context.Background(), srv.URL, &bytes.Buffer{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 80*time.Millisecond {
+		t.Errorf("5 requests in %v, want at least 80ms", elapsed)
+	}
+}
+
+func TestCanceled(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.Backoff = time.Hour
+	o.MaxBackoff = time.Hour
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := NewDownloader(o).DownloadFile(ctx, srv.URL, &bytes.Buffer{})
+	if err == nil {
+		t.Errorf("DownloadFile() expected an error")
+	}
+}
+
+func TestCache(t *testing.T) {
+	var requests, revalidated atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		switch r.URL.Path {
+		case "/etag.gif":
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				revalidated.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+		case "/modified.gif":
+			if r.Header.Get("If-Modified-Since") == "Tue, 01 Jul 2025 12:00:00 GMT" {
+				revalidated.Add(1)
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Last-Modified", "Tue, 01 Jul 2025 12:00:00 GMT")
+		}
+		w.Header().Set("Content-Type", "image/gif")
+		w.Write(gif)
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.CacheDir = t.TempDir()
+	d := NewDownloader(o)
+	for _, name := range []string{"/etag.gif", "/modified.gif", "/plain.gif"} {
+		for range 2 {
+			var buf bytes.Buffer
+			if err := d.DownloadFile(context.Background(), srv.URL+name, &buf); err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(buf.Bytes(), gif) {
+				t.Errorf("%s: unexpected content %q", name, buf.Bytes())
+			}
+		}
+	}
+	if got := revalidated.Load(); got != 2 {
+		t.Errorf("revalidated %d times, want 2", got)
+	}
+
+	// offline mode uses the cache only
+	requests.Store(0)
+	o.Offline = true
+	d = NewDownloader(o)
+	var buf bytes.Buffer
+	if err := d.DownloadFile(context.Background(), srv.URL+"/etag.gif", &buf); err != nil || !bytes.Equal(buf.Bytes(), gif) {
+		t.Errorf("offline cached download: %v, %q", err, buf.Bytes())
+	}
+	if err := d.DownloadFile(context.Background(), srv.URL+"/other.gif", &bytes.Buffer{}); !errors.Is(err, ErrOffline) {
+		t.Errorf("offline download error = %v, want ErrOffline", err)
+	}
+	if got := requests.Load(); got != 0 {
+		t.Errorf("%d requests in offline mode", got)
+	}
+}
+
+func TestCacheServerDown(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write(gif)
+	}))
+	o := testOptions()
+	o.Retries = 0
+	o.CacheDir = t.TempDir()
+	d := NewDownloader(o)
+	url := srv.URL + "/image.gif"
+	if err := d.DownloadFile(context.Background(), url, &bytes.Buffer{}); err != nil {
+		t.Fatal(err)
+	}
+	srv.Close()
+
+	var buf bytes.Buffer
+	if err := d.DownloadFile(context.Background(), url, &buf); err != nil || !bytes.Equal(buf.Bytes(), gif) {
+		t.Errorf("cached download with the server down: %v, %q", err, buf.Bytes())
+	}
+}
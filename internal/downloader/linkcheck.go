@@ -0,0 +1,93 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+)
+
+// LinkStatus classifies the result of a link check
+type LinkStatus string
+
+const (
+	LinkOK       LinkStatus = "ok"
+	LinkRedirect LinkStatus = "redirect"
+	LinkNotFound LinkStatus = "not found"
+	LinkDNS      LinkStatus = "DNS failure"
+	LinkTimeout  LinkStatus = "timeout"
+	LinkError    LinkStatus = "error"
+)
+
+// LinkCheck is the result of a link check
+type LinkCheck struct {
+	Status   LinkStatus
+	Code     int    // HTTP status code, 0 when the server didn't answer
+	Location string // target of the redirection
+	Err      error  // network error
+}
+
+// Dead tells if the link target is gone for good
+func (c LinkCheck) Dead() bool {
+	return c.Status == LinkNotFound || c.Status == LinkDNS
+}
+
+func (c LinkCheck) String() string {
+	switch {
+	case c.Status == LinkRedirect:
+		return fmt.Sprintf("%s (%d) to %s", c.Status, c.Code, c.Location)
+	case c.Code != 0:
+		return fmt.Sprintf("%s (%d)", c.Status, c.Code)
+	case c.Err != nil:
+		return fmt.Sprintf("%s: %s", c.Status, c.Err)
+	}
+	return string(c.Status)
+}
+
+// CheckLink checks the link with a HEAD request, and a GET request when the server refuses HEAD.
+// Redirections aren't followed.
+func (d *Downloader) CheckLink(ctx context.Context, url string) LinkCheck {
+	if d.options.Offline {
+		return LinkCheck{Status: LinkError, Err: ErrOffline}
+	}
+	resp, err := d.send(ctx, &d.checker, http.MethodHead, url, nil)
+	if err == nil && (resp.StatusCode == http.StatusMethodNotAllowed || resp.StatusCode == http.StatusNotImplemented || resp.StatusCode == http.StatusForbidden) {
+		resp.Body.Close()
+		resp, err = d.send(ctx, &d.checker, http.MethodGet, url, nil)
+	}
+	if err != nil {
+		return classifyError(err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	c := LinkCheck{Code: resp.StatusCode}
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		c.Status = LinkOK
+	case resp.StatusCode >= 300 && resp.StatusCode < 400:
+		c.Status = LinkRedirect
+		if loc, err := resp.Location(); err == nil {
+			c.Location = loc.String()
+		}
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		c.Status = LinkNotFound
+	default:
+		c.Status = LinkError
+	}
+	return c
+}
+
+// classifyError tells the DNS failures and the timeouts apart
+func classifyError(err error) LinkCheck {
+	var netErr net.Error
+	switch {
+	case isDNSNotFound(err):
+		return LinkCheck{Status: LinkDNS, Err: err}
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return LinkCheck{Status: LinkTimeout, Err: err}
+	}
+	return LinkCheck{Status: LinkError, Err: err}
+}
@@ -0,0 +1,72 @@
+package downloader
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func TestCheckLink(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/ok":
+		case "/redirect":
+			http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+		case "/nohead":
+			if r.Method == http.MethodHead {
+				w.WriteHeader(http.StatusMethodNotAllowed)
+			}
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+		case "/error":
+			w.WriteHeader(http.StatusUnauthorized)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.Retries = 0
+	o.Timeout = 50 * time.Millisecond
+	d := NewDownloader(o)
+	testCases := []struct {
+		path     string
+		status   LinkStatus
+		code     int
+		location string
+		dead     bool
+	}{
+		{"/ok", LinkOK, 200, "", false},
+		{"/redirect", LinkRedirect, 301, srv.URL + "/ok", false},
+		{"/nohead", LinkOK, 200, "", false},
+		{"/gone", LinkNotFound, 404, "", true},
+		{"/slow", LinkTimeout, 0, "", false},
+		{"/error", LinkError, 401, "", false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			c := d.CheckLink(context.Background(), srv.URL+tc.path)
+			if c.Status != tc.status || c.Code != tc.code || c.Location != tc.location || c.Dead() != tc.dead {
+				t.Errorf("CheckLink(%s) = %+v", tc.path, c)
+			}
+		})
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	dns := &url.Error{Op: "Head", URL: "http://gone.invalid", Err: &net.OpError{Op: "dial", Err: &net.DNSError{Err: "no such host", Name: "gone.invalid", IsNotFound: true}}}
+	if c := classifyError(dns); c.Status != LinkDNS || !c.Dead() {
+		t.Errorf("classifyError(dns) = %+v", c)
+	}
+	if c := classifyError(context.DeadlineExceeded); c.Status != LinkTimeout {
+		t.Errorf("classifyError(deadline) = %+v", c)
+	}
+	if c := classifyError(net.ErrClosed); c.Status != LinkError {
+		t.Errorf("classifyError(closed) = %+v", c)
+	}
+}
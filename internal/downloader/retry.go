@@ -0,0 +1,201 @@
+package downloader
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// do sends the request, waiting for the host's turn and retrying the transient failures.
+// The prepare function, when given, completes the request's headers.
+// The host's slot is released when the response body is closed.
+func (d *Downloader) do(ctx context.Context, method string, link string, prepare func(*http.Request)) (*http.Response, error) {
+	return d.send(ctx, &d.client, method, link, prepare)
+}
+
+// send is do with a given client
+func (d *Downloader) send(ctx context.Context, client *http.Client, method string, link string, prepare func(*http.Request)) (*http.Response, error) {
+	u, err := url.Parse(link)
+	if err != nil {
+		return nil, err
+	}
+	limiter := d.hostLimiter(strings.ToLower(u.Host))
+
+	backoff := d.options.Backoff
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(ctx, method, link, nil)
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("User-Agent", d.options.UserAgent)
+		if prepare != nil {
+			prepare(req)
+		}
+
+		err = limiter.acquire(ctx)
+		if err != nil {
+			return nil, err
+		}
+		resp, err := client.Do(req)
+		if err == nil && !retryable(resp.StatusCode) {
+			resp.Body = &releaseBody{ReadCloser: resp.Body, release: limiter.release}
+			return resp, nil
+		}
+
+		// transient failure
+		wait := backoff
+		if err == nil {
+			if after, ok := retryAfter(resp.Header.Get("Retry-After"), time.Now()); ok {
+				wait = after
+			}
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			err = fmt.Errorf("failed to download file: %s, status: %s", link, resp.Status)
+		}
+		limiter.release()
+		if attempt >= d.options.Retries || ctx.Err() != nil || isPermanent(err) {
+			return nil, err
+		}
+		if d.options.MaxBackoff > 0 && wait > d.options.MaxBackoff {
+			wait = d.options.MaxBackoff
+		}
+		if err := sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+		backoff *= 2
+	}
+}
+
+// isPermanent tells if the failure isn't worth a new attempt:
+// the host name doesn't exist, or the redirection is refused
+func isPermanent(err error) bool {
+	var rejected *RejectedError
+	return isDNSNotFound(err) || errors.As(err, &rejected)
+}
+
+// isDNSNotFound tells if the host name doesn't exist
+func isDNSNotFound(err error) bool {
+	var dnsErr *net.DNSError
+	return errors.As(err, &dnsErr) && dnsErr.IsNotFound
+}
+
+// retryable tells if the status is worth a new attempt
+func retryable(status int) bool {
+	switch status {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// retryAfter reads the Retry-After header, given in seconds or as a HTTP date
+func retryAfter(value string, now time.Time) (time.Duration, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return 0, false
+	}
+	if s, err := strconv.Atoi(value); err == nil {
+		if s < 0 {
+			return 0, false
+		}
+		return time.Duration(s) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		return max(t.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for the duration, or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// hostLimiter bounds the number of simultaneous requests to a host, and spaces them out
+type hostLimiter struct {
+	slots    chan struct{} // nil for no concurrency limit
+	interval time.Duration // minimum delay between two requests
+
+	mu   sync.Mutex
+	next time.Time // time of the next allowed request
+}
+
+func (d *Downloader) hostLimiter(host string) *hostLimiter {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	l, ok := d.hosts[host]
+	if !ok {
+		l = &hostLimiter{}
+		if d.options.HostConcurrency > 0 {
+			l.slots = make(chan struct{}, d.options.HostConcurrency)
+		}
+		if d.options.HostRate > 0 {
+			l.interval = time.Duration(float64(time.Second) / d.options.HostRate)
+		}
+		d.hosts[host] = l
+	}
+	return l
+}
+
+// acquire waits for a free slot and the host's turn
+func (l *hostLimiter) acquire(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	l.mu.Lock()
+	now := time.Now()
+	wait := l.next.Sub(now)
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(l.interval)
+	l.mu.Unlock()
+
+	err := sleep(ctx, wait)
+	if err != nil {
+		l.release()
+	}
+	return err
+}
+
+func (l *hostLimiter) release() {
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// releaseBody frees the host's slot once the body is closed
+type releaseBody struct {
+	io.ReadCloser
+	release func()
+	once    sync.Once
+}
+
+func (b *releaseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.once.Do(b.release)
+	return err
+}
@@ -0,0 +1,143 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// DefaultMaxSize is the default size limit of a download
+const DefaultMaxSize = 50 << 20
+
+// knownPlaceholders lists the images served in place of the deleted photos, one per line:
+// their SHA-256 in hexadecimal, or their dimensions and format, like "400x300 png".
+// The lines starting with # are comments.
+//
+//go:embed placeholders.txt
+var knownPlaceholders string
+
+// placeholderSet returns the known placeholders and the given ones
+func placeholderSet(placeholders []string) map[string]bool {
+	set := map[string]bool{}
+	for _, p := range append(strings.Split(knownPlaceholders, "\n"), placeholders...) {
+		p = strings.ToLower(strings.Join(strings.Fields(p), " "))
+		if p != "" && !strings.HasPrefix(p, "#") {
+			set[p] = true
+		}
+	}
+	return set
+}
+
+// RejectedError is returned when a downloaded content isn't the expected one
+type RejectedError struct {
+	URL    string
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return fmt.Sprintf("%s: rejected, %s", e.URL, e.Reason)
+}
+
+// reject builds the RejectedError of the URL
+func reject(url string, format string, args ...any) error {
+	return &RejectedError{URL: url, Reason: fmt.Sprintf(format, args...)}
+}
+
+// limitedBody fails the read when the body exceeds the size limit
+type limitedBody struct {
+	io.ReadCloser
+	url  string
+	left int64
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if int64(len(p)) > b.left+1 {
+		p = p[:b.left+1] // one more byte tells the body is too large
+	}
+	n, err := b.ReadCloser.Read(p)
+	b.left -= int64(n)
+	if b.left < 0 {
+		return 0, reject(b.url, "larger than the size limit")
+	}
+	return n, err
+}
+
+// limitSize applies the size limit to the response
+func (d *Downloader) limitSize(url string, resp *http.Response) error {
+	if d.options.MaxSize <= 0 {
+		return nil
+	}
+	if resp.ContentLength > d.options.MaxSize {
+		return reject(url, "size of %d bytes larger than the limit of %d bytes", resp.ContentLength, d.options.MaxSize)
+	}
+	resp.Body = &limitedBody{ReadCloser: resp.Body, url: url, left: d.options.MaxSize}
+	return nil
+}
+
+// validateImage checks that the content is a whole image and not a placeholder
+func (d *Downloader) validateImage(url string, content []byte) error {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(content))
+	switch {
+	case errors.Is(err, image.ErrFormat):
+		// formats unknown to the standard library (webp, heic, svg...) are trusted
+	case err != nil:
+		return reject(url, "invalid %s image: %s", strings.TrimPrefix(http.DetectContentType(content), "image/"), err)
+	case cfg.Width <= 1 && cfg.Height <= 1 || d.placeholders[fmt.Sprintf("%dx%d %s", cfg.Width, cfg.Height, format)]:
+		return reject(url, "%dx%d %s placeholder", cfg.Width, cfg.Height, format)
+	}
+	sum := sha256.Sum256(content)
+	if d.placeholders[hex.EncodeToString(sum[:])] {
+		return reject(url, "known placeholder image")
+	}
+	return nil
+}
+
+// checkRedirect refuses the redirections from a public server to a private address
+func checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) >= 10 {
+		return errors.New("stopped after 10 redirects")
+	}
+	if isPrivateHost(req.Context(), via[0].URL.Hostname()) {
+		// a local server may redirect locally
+		return nil
+	}
+	if isPrivateHost(req.Context(), req.URL.Hostname()) {
+		return reject(via[0].URL.String(), "redirected to the private address %s", req.URL.Host)
+	}
+	return nil
+}
+
+// isPrivateHost tells if the host is, or resolves to, a loopback, private or link local address
+func isPrivateHost(ctx context.Context, host string) bool {
+	var ips []net.IP
+	if ip := net.ParseIP(host); ip != nil {
+		ips = append(ips, ip)
+	} else {
+		addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+		if err != nil {
+			// the request will fail anyway
+			return false
+		}
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsUnspecified() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package downloader
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestValidateImage(t *testing.T) {
+	pixel := []byte("GIF89a\x01\x00\x01\x00\x00\x00\x00;")
+	placeholder := []byte("GIF89a\x10\x00\x10\x00\x00\x00\x00;")
+	sum := sha256.Sum256(placeholder)
+	o := testOptions()
+	o.Placeholders = []string{hex.EncodeToString(sum[:])}
+	d := NewDownloader(o)
+
+	testCases := []struct {
+		name    string
+		content []byte
+		reason  string // empty when accepted
+	}{
+		{"gif", gif, ""},
+		{"pixel", pixel, "1x1 gif placeholder"},
+		{"placeholder", placeholder, "known placeholder image"},
+		{"truncated png", []byte("\x89PNG\x0D\x0A\x1A\x0A\x00\x00"), "invalid png image"},
+		{"webp", []byte("RIFF\x00\x00\x00\x00WEBPVP8 "), ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := d.validateImage("http://example.com/img", tc.content)
+			var rejected *RejectedError
+			switch {
+			case tc.reason == "" && err != nil:
+				t.Errorf("validateImage() = %v; want accepted", err)
+			case tc.reason != "" && (!errors.As(err, &rejected) || !strings.HasPrefix(rejected.Reason, tc.reason)):
+				t.Errorf("validateImage() = %v; want rejected with %q", err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestKnownPlaceholders(t *testing.T) {
+	deleted := []byte("GIF89a\x10\x00\x10\x00\x00\x00\x00;")
+	private := []byte("GIF89a\x20\x00\x18\x00\x00\x00\x00;")
+	sum := sha256.Sum256(deleted)
+	entry := regexp.MustCompile(`^([0-9a-f]{64}|\d+x\d+ (gif|jpeg|png))$`)
+	for p := range placeholderSet(nil) {
+		if !entry.MatchString(p) {
+			t.Errorf("invalid entry %q in placeholders.txt", p)
+		}
+	}
+	defer func(known string) { knownPlaceholders = known }(knownPlaceholders)
+	knownPlaceholders = "# deleted photo\n" + strings.ToUpper(hex.EncodeToString(sum[:])) + "\n\n# private photo\n32x24  GIF\n"
+	d := NewDownloader(testOptions())
+
+	testCases := []struct {
+		name    string
+		content []byte
+		reason  string // empty when accepted
+	}{
+		{"hash", deleted, "known placeholder image"},
+		{"dimensions", private, "32x24 gif placeholder"},
+		{"image", gif, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := d.validateImage("http://example.com/img", tc.content)
+			var rejected *RejectedError
+			switch {
+			case tc.reason == "" && err != nil:
+				t.Errorf("validateImage() = %v; want accepted", err)
+			case tc.reason != "" && (!errors.As(err, &rejected) || rejected.Reason != tc.reason):
+				t.Errorf("validateImage() = %v; want rejected with %q", err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestDownloadRejections(t *testing.T) {
+	big := bytes.Repeat([]byte{0}, 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/img.gif":
+			w.Write(gif)
+		case "/big.gif":
+			w.Header().Set("Content-Type", "image/gif")
+			w.Write(big)
+		case "/chunked.gif":
+			w.Header().Set("Content-Type", "image/gif")
+			w.Write(big[:1024])
+			w.(http.Flusher).Flush()
+			w.Write(big[1024:])
+		case "/page.html":
+			fmt.Fprint(w, `<html><head><meta property="og:image" content="/img.gif"></head><body><img src="/logo.png"></body></html>`)
+		case "/login.html":
+			fmt.Fprint(w, `<html><body><img src="/img.gif"><form><input type="password" name="p"></form></body></html>`)
+		case "/loop.html":
+			fmt.Fprint(w, `<html><body><img src="/loop.html"></body></html>`)
+		case "/doc.pdf":
+			w.Header().Set("Content-Type", "application/pdf")
+			fmt.Fprint(w, "%PDF-1.4")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	o := testOptions()
+	o.MaxSize = 1500
+	d := NewDownloader(o)
+	testCases := []struct {
+		path   string
+		reason string // empty when accepted
+	}{
+		{"/img.gif", ""},
+		{"/page.html", ""},
+		{"/big.gif", "size of 2048 bytes larger than the limit"},
+		{"/chunked.gif", "larger than the size limit"},
+		{"/login.html", "login page"},
+		{"/loop.html", "HTML page instead of an image"},
+		{"/doc.pdf", "unsupported content type application/pdf"},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.path, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := d.DownloadFile(context.Background(), srv.URL+tc.path, &buf)
+			var rejected *RejectedError
+			switch {
+			case tc.reason == "":
+				if err != nil || !bytes.Equal(buf.Bytes(), gif) {
+					t.Errorf("DownloadFile() = %v, %q; want the image", err, buf.Bytes())
+				}
+			case !errors.As(err, &rejected) || !strings.HasPrefix(rejected.Reason, tc.reason):
+				t.Errorf("DownloadFile() = %v; want rejected with %q", err, tc.reason)
+			}
+		})
+	}
+}
+
+func TestCheckRedirect(t *testing.T) {
+	request := func(link string) *http.Request {
+		u, _ := url.Parse(link)
+		return (&http.Request{URL: u}).WithContext(context.Background())
+	}
+	testCases := []struct {
+		from, to string
+		rejected bool
+	}{
+		{"http://93.184.216.34/img", "http://93.184.216.35/img", false},
+		{"http://93.184.216.34/img", "http://10.0.0.1/admin", true},
+		{"http://93.184.216.34/img", "http://127.0.0.1:8080/", true},
+		{"http://93.184.216.34/img", "http://[fe80::1]/", true},
+		{"http://127.0.0.1/img", "http://127.0.0.1/other", false},
+	}
+	for _, tc := range testCases {
+		err := checkRedirect(request(tc.to), []*http.Request{request(tc.from)})
+		var rejected *RejectedError
+		if errors.As(err, &rejected) != tc.rejected {
+			t.Errorf("checkRedirect(%s -> %s) = %v", tc.from, tc.to, err)
+		}
+	}
+}
+
+func TestLimitedBody(t *testing.T) {
+	testCases := []struct {
+		size     int
+		rejected bool
+	}{
+		{999, false},
+		{1000, false},
+		{1001, true},
+		{5000, true},
+	}
+	for _, tc := range testCases {
+		t.Run(fmt.Sprint(tc.size), func(t *testing.T) {
+			b := &limitedBody{ReadCloser: io.NopCloser(bytes.NewReader(make([]byte, tc.size))), url: "http://example.com/img", left: 1000}
+			got, err := io.ReadAll(b)
+			var rejected *RejectedError
+			switch {
+			case tc.rejected && !errors.As(err, &rejected):
+				t.Errorf("ReadAll() error = %v; want rejected", err)
+			case !tc.rejected && (err != nil || len(got) != tc.size):
+				t.Errorf("ReadAll() = %d bytes, %v; want %d bytes", len(got), err, tc.size)
+			}
+		})
+	}
+}
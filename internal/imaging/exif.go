@@ -0,0 +1,418 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+)
+
+// JPEG markers
+const (
+	markerSOI  = 0xD8
+	markerAPP1 = 0xE1
+	markerSOS  = 0xDA
+	markerEOI  = 0xD9
+)
+
+// EXIF tags
+const (
+	tagOrientation = 0x0112
+)
+
+var exifHeader = []byte("Exif\x00\x00")
+
+// jpegSegment is a marker segment of a JPEG file, before the image data
+type jpegSegment struct {
+	marker byte
+	start  int // offset of the 0xFF byte of the marker
+	end    int // offset of the byte following the segment
+}
+
+// payload returns the segment's data, after the marker and the length
+func (s jpegSegment) payload(data []byte) []byte {
+	return data[s.start+4 : s.end]
+}
+
+// jpegSegments lists the marker segments up to the start of the scan.
+// It returns nil when data isn't a JPEG file.
+func jpegSegments(data []byte) []jpegSegment {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != markerSOI {
+		return nil
+	}
+	segments := []jpegSegment{}
+	p := 2
+	for p+4 <= len(data) {
+		if data[p] != 0xFF {
+			return nil
+		}
+		marker := data[p+1]
+		if marker == 0xFF {
+			// fill byte
+			p++
+			continue
+		}
+		if marker == markerSOS || marker == markerEOI {
+			break
+		}
+		l := int(binary.BigEndian.Uint16(data[p+2:]))
+		if l < 2 || p+2+l > len(data) {
+			return nil
+		}
+		segments = append(segments, jpegSegment{marker: marker, start: p, end: p + 2 + l})
+		p += 2 + l
+	}
+	return segments
+}
+
+// exifData returns the TIFF structure of the JPEG's EXIF segment
+func exifData(data []byte) []byte {
+	for _, s := range jpegSegments(data) {
+		if s.marker == markerAPP1 && bytes.HasPrefix(s.payload(data), exifHeader) {
+			return s.payload(data)[len(exifHeader):]
+		}
+	}
+	return nil
+}
+
+// byteOrder reads and appends integers in the byte order of a TIFF structure
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// tiffReader reads the IFDs of a TIFF structure
+type tiffReader struct {
+	data  []byte
+	order byteOrder
+}
+
+func newTiffReader(data []byte) *tiffReader {
+	if len(data) < 8 {
+		return nil
+	}
+	var order byteOrder
+	switch string(data[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return nil
+	}
+	if order.Uint16(data[2:]) != 42 {
+		return nil
+	}
+	return &tiffReader{data: data, order: order}
+}
+
+// firstIFD returns the offset of IFD0
+func (t *tiffReader) firstIFD() int {
+	return int(t.order.Uint32(t.data[4:]))
+}
+
+// ifdEntry is a 12 bytes entry of an IFD
+type ifdEntry struct {
+	offset int // offset of the entry in the TIFF structure
+	tag    uint16
+	typ    uint16
+	count  uint32
+}
+
+// entries returns the IFD's entries, and the offset of the next IFD
+func (t *tiffReader) entries(ifd int) ([]ifdEntry, int) {
+	if ifd <= 0 || ifd+2 > len(t.data) {
+		return nil, 0
+	}
+	n := int(t.order.Uint16(t.data[ifd:]))
+	if ifd+2+n*12+4 > len(t.data) {
+		return nil, 0
+	}
+	l := make([]ifdEntry, n)
+	for i := range n {
+		o := ifd + 2 + i*12
+		l[i] = ifdEntry{
+			offset: o,
+			tag:    t.order.Uint16(t.data[o:]),
+			typ:    t.order.Uint16(t.data[o+2:]),
+			count:  t.order.Uint32(t.data[o+4:]),
+		}
+	}
+	next := int(t.order.Uint32(t.data[ifd+2+n*12:]))
+	return l, next
+}
+
+// short returns the value of a SHORT entry
+func (t *tiffReader) short(e ifdEntry) int {
+	return int(t.order.Uint16(t.data[e.offset+8:]))
+}
+
+// Orientation returns the EXIF orientation of a JPEG image, 1 when unknown
+func Orientation(data []byte) int {
+	t := newTiffReader(exifData(data))
+	if t == nil {
+		return 1
+	}
+	entries, _ := t.entries(t.firstIFD())
+	for _, e := range entries {
+		if e.tag == tagOrientation {
+			if o := t.short(e); o >= 1 && o <= 8 {
+				return o
+			}
+		}
+	}
+	return 1
+}
+
+// EXIF tags kept by Sanitize
+const (
+	tagMake              = 0x010F
+	tagModel             = 0x0110
+	tagDateTime          = 0x0132
+	tagExifIFD           = 0x8769
+	tagGPSIFD            = 0x8825
+	tagDateTimeOriginal  = 0x9003
+	tagDateTimeDigitized = 0x9004
+	tagOffsetTime        = 0x9010
+	tagOffsetTimeOrig    = 0x9011
+	tagOffsetTimeDigit   = 0x9012
+	tagArtist            = 0x013B
+	tagCopyright         = 0x8298
+	tagMakerNote         = 0x927C
+	tagUserComment       = 0x9286
+	tagImageUniqueID     = 0xA420
+	tagCameraOwnerName   = 0xA430
+	tagBodySerialNumber  = 0xA431
+	tagLensSerialNumber  = 0xA435
+)
+
+// removedCategory names the private data held by a tag
+var removedCategory = map[uint16]string{
+	tagGPSIFD:           "GPS",
+	tagArtist:           "owner",
+	tagCopyright:        "owner",
+	tagCameraOwnerName:  "owner",
+	tagUserComment:      "comment",
+	tagMakerNote:        "maker note",
+	tagImageUniqueID:    "serial number",
+	tagBodySerialNumber: "serial number",
+	tagLensSerialNumber: "serial number",
+}
+
+var (
+	ifd0Kept = map[uint16]bool{tagOrientation: true, tagMake: true, tagModel: true, tagDateTime: true}
+	exifKept = map[uint16]bool{tagDateTimeOriginal: true, tagDateTimeDigitized: true, tagOffsetTime: true, tagOffsetTimeOrig: true, tagOffsetTimeDigit: true}
+)
+
+var xmpHeader = []byte("http://ns.adobe.com/xap/1.0/\x00")
+
+const markerAPP13 = 0xED
+
+// Sanitize removes the private data of a JPEG file without re-encoding the image:
+// the EXIF segment is rebuilt with the orientation, the camera model and the capture date only,
+// the XMP and IPTC segments are dropped.
+//
+// It returns the sanitized file and the categories of private data removed.
+// The data is returned untouched when nothing private is found, or when it isn't a JPEG file.
+func Sanitize(data []byte) ([]byte, []string) {
+	segments := jpegSegments(data)
+	if segments == nil {
+		return data, nil
+	}
+
+	removed := map[string]bool{}
+	out := make([]byte, 0, len(data))
+	out = append(out, data[:2]...)
+	last := 2
+	for _, s := range segments {
+		payload := s.payload(data)
+		var replacement []byte
+		switch {
+		case s.marker == markerAPP1 && bytes.HasPrefix(payload, exifHeader):
+			tiff, private := minimalTIFF(payload[len(exifHeader):], true)
+			if len(private) == 0 {
+				continue
+			}
+			for _, c := range private {
+				removed[c] = true
+			}
+			replacement = exifSegment(tiff)
+		case s.marker == markerAPP1 && bytes.HasPrefix(payload, xmpHeader):
+			removed["XMP"] = true
+		case s.marker == markerAPP13:
+			removed["IPTC"] = true
+		default:
+			continue
+		}
+		out = append(out, data[last:s.start]...)
+		out = append(out, replacement...)
+		last = s.end
+	}
+	if len(removed) == 0 {
+		return data, nil
+	}
+	out = append(out, data[last:]...)
+
+	categories := make([]string, 0, len(removed))
+	for c := range removed {
+		categories = append(categories, c)
+	}
+	slices.Sort(categories)
+	return out, categories
+}
+
+// CopyCaptureDate inserts into the JPEG dst the camera model and capture date found
+// in the EXIF data of the JPEG src. It is used to keep the date of re-encoded images.
+func CopyCaptureDate(src []byte, dst []byte) []byte {
+	tiff, _ := minimalTIFF(exifData(src), false)
+	if tiff == nil || jpegSegments(dst) == nil {
+		return dst
+	}
+	out := make([]byte, 0, len(dst)+len(tiff)+16)
+	out = append(out, dst[:2]...)
+	out = append(out, exifSegment(tiff)...)
+	return append(out, dst[2:]...)
+}
+
+// exifSegment wraps a TIFF structure into an APP1 segment
+func exifSegment(tiff []byte) []byte {
+	if tiff == nil {
+		return nil
+	}
+	seg := []byte{0xFF, markerAPP1}
+	seg = binary.BigEndian.AppendUint16(seg, uint16(2+len(exifHeader)+len(tiff)))
+	seg = append(seg, exifHeader...)
+	return append(seg, tiff...)
+}
+
+// tiffEntry is an IFD entry with its value
+type tiffEntry struct {
+	tag   uint16
+	typ   uint16
+	count uint32
+	value []byte // raw value in the byte order of the TIFF structure
+}
+
+// typeSizes gives the size in bytes of the TIFF types
+var typeSizes = map[uint16]int{1: 1, 2: 1, 3: 2, 4: 4, 5: 8, 6: 1, 7: 1, 8: 2, 9: 4, 10: 8, 11: 4, 12: 8}
+
+// value returns the raw value of the entry
+func (t *tiffReader) value(e ifdEntry) []byte {
+	size := typeSizes[e.typ] * int(e.count)
+	if size == 0 {
+		return nil
+	}
+	if size <= 4 {
+		return t.data[e.offset+8 : e.offset+8+size]
+	}
+	o := int(t.order.Uint32(t.data[e.offset+8:]))
+	if o < 0 || o+size > len(t.data) {
+		return nil
+	}
+	return t.data[o : o+size]
+}
+
+// minimalTIFF builds a TIFF structure with the kept tags of the EXIF data,
+// and lists the categories of private data found.
+// When keepOrientation is false, the orientation isn't kept.
+func minimalTIFF(data []byte, keepOrientation bool) ([]byte, []string) {
+	t := newTiffReader(data)
+	if t == nil {
+		return nil, nil
+	}
+	private := map[string]bool{}
+	collect := func(ifd int, kept map[uint16]bool) []tiffEntry {
+		entries, _ := t.entries(ifd)
+		l := []tiffEntry{}
+		for _, e := range entries {
+			if c, ok := removedCategory[e.tag]; ok {
+				private[c] = true
+			}
+			if !kept[e.tag] || (e.tag == tagOrientation && !keepOrientation) {
+				continue
+			}
+			if v := t.value(e); v != nil {
+				l = append(l, tiffEntry{tag: e.tag, typ: e.typ, count: e.count, value: v})
+			}
+		}
+		return l
+	}
+
+	ifd0 := collect(t.firstIFD(), ifd0Kept)
+	exif := []tiffEntry{}
+	entries, _ := t.entries(t.firstIFD())
+	for _, e := range entries {
+		if e.tag == tagExifIFD {
+			exif = collect(int(t.order.Uint32(t.data[e.offset+8:])), exifKept)
+		}
+	}
+
+	categories := make([]string, 0, len(private))
+	for c := range private {
+		categories = append(categories, c)
+	}
+	if len(ifd0) == 0 && len(exif) == 0 {
+		return nil, categories
+	}
+	return writeTIFF(t.order, ifd0, exif), categories
+}
+
+// writeTIFF writes a TIFF structure with the IFD0 entries, and the EXIF IFD entries
+func writeTIFF(order byteOrder, ifd0 []tiffEntry, exif []tiffEntry) []byte {
+	out := []byte{}
+	if order == binary.LittleEndian {
+		out = append(out, "II"...)
+	} else {
+		out = append(out, "MM"...)
+	}
+	out = order.AppendUint16(out, 42)
+	out = order.AppendUint32(out, 8)
+
+	if len(exif) > 0 {
+		// the pointer value is patched once the EXIF IFD position is known
+		ifd0 = append(ifd0, tiffEntry{tag: tagExifIFD, typ: 4, count: 1, value: make([]byte, 4)})
+	}
+	slices.SortFunc(ifd0, func(a, b tiffEntry) int { return int(a.tag) - int(b.tag) })
+	slices.SortFunc(exif, func(a, b tiffEntry) int { return int(a.tag) - int(b.tag) })
+
+	out, pointer := appendIFD(out, order, ifd0)
+	if len(exif) > 0 {
+		if len(out)%2 == 1 {
+			out = append(out, 0)
+		}
+		order.PutUint32(out[pointer:], uint32(len(out)))
+		out, _ = appendIFD(out, order, exif)
+	}
+	return out
+}
+
+// appendIFD appends the IFD and its values, and returns the offset of the value
+// field of the EXIF IFD pointer, if any
+func appendIFD(out []byte, order byteOrder, entries []tiffEntry) ([]byte, int) {
+	start := len(out)
+	dataOffset := start + 2 + len(entries)*12 + 4
+	data := []byte{}
+	pointer := 0
+
+	out = order.AppendUint16(out, uint16(len(entries)))
+	for _, e := range entries {
+		out = order.AppendUint16(out, e.tag)
+		out = order.AppendUint16(out, e.typ)
+		out = order.AppendUint32(out, e.count)
+		if e.tag == tagExifIFD {
+			pointer = len(out)
+		}
+		if len(e.value) <= 4 {
+			v := make([]byte, 4)
+			copy(v, e.value)
+			out = append(out, v...)
+			continue
+		}
+		out = order.AppendUint32(out, uint32(dataOffset+len(data)))
+		data = append(data, e.value...)
+		if len(data)%2 == 1 {
+			data = append(data, 0)
+		}
+	}
+	out = order.AppendUint32(out, 0) // no next IFD
+	return append(out, data...), pointer
+}
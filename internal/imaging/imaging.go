@@ -0,0 +1,107 @@
+// Package imaging prepares the photos for the web: EXIF orientation,
+// resizing and re-encoding of JPEG, PNG and GIF files, in pure Go.
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+)
+
+// Options for the image processing
+type Options struct {
+	MaxDimension int // maximum width or height of the web copy, 0 to keep the size
+	Quality      int // JPEG quality, 1-100
+}
+
+// Result of the image processing
+type Result struct {
+	Data      []byte // encoded image, the original data when nothing has been done
+	Format    string // "jpeg", "png" or "gif"
+	Width     int
+	Height    int
+	Processed bool // the image has been rotated or resized
+}
+
+// Process applies the EXIF orientation and reduces the image to the maximum dimension.
+// The original data is returned untouched when the image doesn't need changes,
+// or when it is an animated GIF.
+func Process(data []byte, opts Options) (*Result, error) {
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	res := &Result{Data: data, Format: format, Width: cfg.Width, Height: cfg.Height}
+
+	orientation := 1
+	switch format {
+	case "jpeg":
+		orientation = Orientation(data)
+	case "png":
+	case "gif":
+		g, err := gif.DecodeAll(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		if len(g.Image) > 1 {
+			// resizing animations isn't worth it
+			return res, nil
+		}
+	default:
+		return nil, fmt.Errorf("unsupported image format: %s", format)
+	}
+
+	w, h := cfg.Width, cfg.Height
+	if orientation >= 5 {
+		w, h = h, w
+	}
+	tw, th := fit(w, h, opts.MaxDimension)
+	if orientation == 1 && tw == w && th == h {
+		return res, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	dst := orient(toNRGBA(img), orientation)
+	if tw != w || th != h {
+		dst = resize(dst, tw, th)
+	}
+
+	buf := bytes.Buffer{}
+	switch format {
+	case "jpeg":
+		quality := opts.Quality
+		if quality <= 0 || quality > 100 {
+			quality = jpeg.DefaultQuality
+		}
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+	case "png":
+		err = png.Encode(&buf, dst)
+	case "gif":
+		err = gif.Encode(&buf, dst, nil)
+	}
+	if err != nil {
+		return nil, err
+	}
+	res.Data = buf.Bytes()
+	res.Width = dst.Bounds().Dx()
+	res.Height = dst.Bounds().Dy()
+	res.Processed = true
+	return res, nil
+}
+
+// fit returns the dimensions of the image reduced to fit into limit x limit, keeping the aspect ratio
+func fit(w, h, limit int) (int, int) {
+	if limit <= 0 || (w <= limit && h <= limit) {
+		return w, h
+	}
+	if w >= h {
+		return limit, max(1, (h*limit+w/2)/w)
+	}
+	return max(1, (w*limit+h/2)/h), limit
+}
@@ -0,0 +1,237 @@
+package imaging
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"slices"
+	"testing"
+)
+
+// newJPEG encodes a w x h JPEG, and inserts an EXIF segment with the given orientation
+func newJPEG(t *testing.T, w, h int, orientation int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := range h {
+		for x := range w {
+			img.Set(x, y, color.RGBA{uint8(x * 255 / w), uint8(y * 255 / h), 128, 255})
+		}
+	}
+	buf := bytes.Buffer{}
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	if orientation == 0 {
+		return data
+	}
+
+	// TIFF structure with IFD0 holding the orientation
+	tiff := []byte("MM\x00\x2a\x00\x00\x00\x08")
+	tiff = binary.BigEndian.AppendUint16(tiff, 1) // entries
+	tiff = binary.BigEndian.AppendUint16(tiff, tagOrientation)
+	tiff = binary.BigEndian.AppendUint16(tiff, 3) // SHORT
+	tiff = binary.BigEndian.AppendUint32(tiff, 1)
+	tiff = binary.BigEndian.AppendUint16(tiff, uint16(orientation))
+	tiff = binary.BigEndian.AppendUint16(tiff, 0)
+	tiff = binary.BigEndian.AppendUint32(tiff, 0) // no next IFD
+
+	app1 := []byte{0xFF, markerAPP1}
+	app1 = binary.BigEndian.AppendUint16(app1, uint16(2+len(exifHeader)+len(tiff)))
+	app1 = append(app1, exifHeader...)
+	app1 = append(app1, tiff...)
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, app1...)
+	return append(out, data[2:]...)
+}
+
+func TestOrientation(t *testing.T) {
+	for _, o := range []int{1, 3, 6, 8} {
+		if got := Orientation(newJPEG(t, 4, 2, o)); got != o {
+			t.Errorf("Orientation() = %d; want %d", got, o)
+		}
+	}
+	if got := Orientation(newJPEG(t, 4, 2, 0)); got != 1 {
+		t.Errorf("Orientation() without EXIF = %d; want 1", got)
+	}
+	if got := Orientation([]byte("not a jpeg")); got != 1 {
+		t.Errorf("Orientation() of garbage = %d; want 1", got)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	testCases := []struct {
+		name          string
+		data          []byte
+		maxDimension  int
+		width, height int
+		processed     bool
+	}{
+		{"untouched", newJPEG(t, 400, 200, 1), 1000, 400, 200, false},
+		{"resized", newJPEG(t, 400, 200, 1), 100, 100, 50, true},
+		{"rotated", newJPEG(t, 400, 200, 6), 0, 200, 400, true},
+		{"rotated_resized", newJPEG(t, 400, 200, 8), 100, 50, 100, true},
+		{"png", newPNG(t, 300, 600), 150, 75, 150, true},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			res, err := Process(tc.data, Options{MaxDimension: tc.maxDimension, Quality: 80})
+			if err != nil {
+				t.Fatalf("Process failed: %v", err)
+			}
+			if res.Width != tc.width || res.Height != tc.height {
+				t.Errorf("got %dx%d; want %dx%d", res.Width, res.Height, tc.width, tc.height)
+			}
+			if res.Processed != tc.processed {
+				t.Errorf("processed = %v; want %v", res.Processed, tc.processed)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(res.Data))
+			if err != nil {
+				t.Fatalf("can't decode the result: %v", err)
+			}
+			if cfg.Width != tc.width || cfg.Height != tc.height {
+				t.Errorf("encoded image is %dx%d; want %dx%d", cfg.Width, cfg.Height, tc.width, tc.height)
+			}
+		})
+	}
+}
+
+func newPNG(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOrient(t *testing.T) {
+	red := color.NRGBA{255, 0, 0, 255}
+	blue := color.NRGBA{0, 0, 255, 255}
+	src := image.NewNRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, red)
+	src.Set(1, 0, blue)
+
+	testCases := []struct {
+		orientation int
+		first, last color.NRGBA // first and last pixels of the result
+		vertical    bool
+	}{
+		{1, red, blue, false},
+		{2, blue, red, false},
+		{3, blue, red, false},
+		{6, red, blue, true},
+		{8, blue, red, true},
+	}
+	for _, tc := range testCases {
+		dst := orient(src, tc.orientation)
+		b := dst.Bounds()
+		if (b.Dy() == 2) != tc.vertical {
+			t.Errorf("orientation %d: unexpected bounds %v", tc.orientation, b)
+			continue
+		}
+		if dst.NRGBAAt(0, 0) != tc.first || dst.NRGBAAt(b.Dx()-1, b.Dy()-1) != tc.last {
+			t.Errorf("orientation %d: unexpected pixels %v, %v", tc.orientation, dst.NRGBAAt(0, 0), dst.NRGBAAt(b.Dx()-1, b.Dy()-1))
+		}
+	}
+}
+
+// newPrivateJPEG encodes a JPEG with GPS, owner and serial number tags, an orientation and a capture date
+func newPrivateJPEG(t *testing.T) []byte {
+	t.Helper()
+	ascii := func(tag uint16, s string) tiffEntry {
+		return tiffEntry{tag: tag, typ: 2, count: uint32(len(s) + 1), value: append([]byte(s), 0)}
+	}
+	ifd0 := []tiffEntry{
+		{tag: tagOrientation, typ: 3, count: 1, value: []byte{0, 6}},
+		ascii(tagModel, "Camera"),
+		ascii(tagArtist, "John Doe"),
+		{tag: tagGPSIFD, typ: 4, count: 1, value: []byte{0, 0, 0, 8}},
+	}
+	exif := []tiffEntry{
+		ascii(tagDateTimeOriginal, "2010:07:14 12:34:56"),
+		ascii(tagBodySerialNumber, "123456789"),
+	}
+	data := newJPEG(t, 4, 2, 0)
+	out := append([]byte{}, data[:2]...)
+	out = append(out, exifSegment(writeTIFF(binary.BigEndian, ifd0, exif))...)
+	out = append(out, 0xFF, markerAPP13, 0, 6, 'I', 'P', 'T', 'C')
+	return append(out, data[2:]...)
+}
+
+// exifTags returns the tags of IFD0 and the EXIF IFD with their values
+func exifTags(data []byte) map[uint16]string {
+	tags := map[uint16]string{}
+	t := newTiffReader(exifData(data))
+	if t == nil {
+		return tags
+	}
+	entries, _ := t.entries(t.firstIFD())
+	for _, e := range entries {
+		tags[e.tag] = string(bytes.TrimRight(t.value(e), "\x00"))
+		if e.tag == tagExifIFD {
+			sub, _ := t.entries(int(t.order.Uint32(t.data[e.offset+8:])))
+			for _, e := range sub {
+				tags[e.tag] = string(bytes.TrimRight(t.value(e), "\x00"))
+			}
+		}
+	}
+	return tags
+}
+
+func TestSanitize(t *testing.T) {
+	data := newPrivateJPEG(t)
+	out, removed := Sanitize(data)
+	if want := []string{"GPS", "IPTC", "owner", "serial number"}; !slices.Equal(removed, want) {
+		t.Errorf("removed = %v; want %v", removed, want)
+	}
+	if _, _, err := image.DecodeConfig(bytes.NewReader(out)); err != nil {
+		t.Fatalf("can't decode the sanitized file: %v", err)
+	}
+	if got := Orientation(out); got != 6 {
+		t.Errorf("Orientation() = %d; want 6", got)
+	}
+	tags := exifTags(out)
+	for _, tag := range []uint16{tagGPSIFD, tagArtist, tagBodySerialNumber} {
+		if _, ok := tags[tag]; ok {
+			t.Errorf("tag %#04x not removed", tag)
+		}
+	}
+	if got := tags[tagDateTimeOriginal]; got != "2010:07:14 12:34:56" {
+		t.Errorf("capture date = %q; want 2010:07:14 12:34:56", got)
+	}
+	if got := tags[tagModel]; got != "Camera" {
+		t.Errorf("model = %q; want Camera", got)
+	}
+
+	again, removed := Sanitize(out)
+	if len(removed) != 0 || !bytes.Equal(again, out) {
+		t.Errorf("sanitized file changed again, removed %v", removed)
+	}
+	plain := newJPEG(t, 4, 2, 3)
+	if got, removed := Sanitize(plain); len(removed) != 0 || !bytes.Equal(got, plain) {
+		t.Errorf("file without private data changed, removed %v", removed)
+	}
+}
+
+func TestCopyCaptureDate(t *testing.T) {
+	out := CopyCaptureDate(newPrivateJPEG(t), newJPEG(t, 4, 2, 0))
+	if _, _, err := image.DecodeConfig(bytes.NewReader(out)); err != nil {
+		t.Fatalf("can't decode the result: %v", err)
+	}
+	tags := exifTags(out)
+	if got := tags[tagDateTimeOriginal]; got != "2010:07:14 12:34:56" {
+		t.Errorf("capture date = %q; want 2010:07:14 12:34:56", got)
+	}
+	if _, ok := tags[tagOrientation]; ok {
+		t.Errorf("orientation copied to the re-encoded image")
+	}
+	if _, ok := tags[tagGPSIFD]; ok {
+		t.Errorf("GPS copied to the re-encoded image")
+	}
+}
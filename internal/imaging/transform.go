@@ -0,0 +1,138 @@
+package imaging
+
+import (
+	"image"
+	"image/draw"
+)
+
+// toNRGBA converts the image into a NRGBA image with origin at 0,0
+func toNRGBA(img image.Image) *image.NRGBA {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Src)
+	return dst
+}
+
+// orient applies the EXIF orientation to the image
+//
+//	1: normal          2: flipped horizontally   3: rotated 180°     4: flipped vertically
+//	5: transposed      6: rotated 90° CW         7: transversed      8: rotated 90° CCW
+func orient(src *image.NRGBA, orientation int) *image.NRGBA {
+	if orientation <= 1 || orientation > 8 {
+		return src
+	}
+	w, h := src.Bounds().Dx(), src.Bounds().Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := range dh {
+		for x := range dw {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			copy(dst.Pix[y*dst.Stride+x*4:y*dst.Stride+x*4+4], src.Pix[sy*src.Stride+sx*4:sy*src.Stride+sx*4+4])
+		}
+	}
+	return dst
+}
+
+// weight is the contribution of a source pixel to a destination pixel
+type weight struct {
+	index  int
+	weight float32
+}
+
+// boxWeights computes for each destination pixel the source pixels it covers,
+// weighted by their coverage. This area averaging gives good results when reducing images.
+func boxWeights(srcSize, dstSize int) [][]weight {
+	scale := float64(srcSize) / float64(dstSize)
+	weights := make([][]weight, dstSize)
+	for i := range dstSize {
+		start := float64(i) * scale
+		end := start + scale
+		for j := int(start); j < srcSize && float64(j) < end; j++ {
+			cover := min(end, float64(j+1)) - max(start, float64(j))
+			if cover > 0 {
+				weights[i] = append(weights[i], weight{index: j, weight: float32(cover / scale)})
+			}
+		}
+	}
+	return weights
+}
+
+// resize reduces the image to the given dimensions.
+// Colors are premultiplied by alpha during the averaging to avoid dark fringes.
+func resize(src *image.NRGBA, width, height int) *image.NRGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	xw := boxWeights(sw, width)
+	yw := boxWeights(sh, height)
+
+	// horizontal pass
+	tmp := make([]float32, width*sh*4)
+	for y := range sh {
+		row := src.Pix[y*src.Stride:]
+		for x, ws := range xw {
+			var r, g, b, a float32
+			for _, w := range ws {
+				p := row[w.index*4:]
+				pa := float32(p[3]) * w.weight
+				r += float32(p[0]) * pa
+				g += float32(p[1]) * pa
+				b += float32(p[2]) * pa
+				a += pa
+			}
+			t := tmp[(y*width+x)*4:]
+			t[0], t[1], t[2], t[3] = r, g, b, a
+		}
+	}
+
+	// vertical pass
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for y, ws := range yw {
+		for x := range width {
+			var r, g, b, a float32
+			for _, w := range ws {
+				t := tmp[(w.index*width+x)*4:]
+				r += t[0] * w.weight
+				g += t[1] * w.weight
+				b += t[2] * w.weight
+				a += t[3] * w.weight
+			}
+			d := dst.Pix[y*dst.Stride+x*4:]
+			if a > 0 {
+				d[0] = clamp(r / a)
+				d[1] = clamp(g / a)
+				d[2] = clamp(b / a)
+			}
+			d[3] = clamp(a)
+		}
+	}
+	return dst
+}
+
+func clamp(v float32) uint8 {
+	v += 0.5
+	if v < 0 {
+		return 0
+	}
+	if v > 255 {
+		return 255
+	}
+	return uint8(v)
+}
@@ -3,10 +3,8 @@ package blogger
 import (
 	"context"
 	"encoding/csv"
-	"encoding/json"
 	"io/fs"
 	"path"
-	"strconv"
 	"strings"
 	"time"
 
@@ -119,7 +117,7 @@ func (to *BloggerTakeout) scanBlogs(_ context.Context, filePath string) error {
 			case "settings.csv", "feed.atom":
 				continue
 			default:
-				to.ressources.Add(to.vfs, blog.Title, path.Join(filePath, b.Name()), file, nil)
+				to.ressources.Add(to.vfs, resources.ContainerKey(resources.BloggerSource, blog.Title), path.Join(filePath, b.Name()), file, nil)
 			}
 		}
 	}
@@ -182,6 +180,8 @@ func (to *BloggerTakeout) scanAlbums(ctx context.Context, filePath string) error
 		if err != nil {
 			return err
 		}
+		album := strings.TrimSuffix(a.Name(), "/")
+		albumMetadata := ""
 
 		for _, file := range files {
 			if file.IsDir() {
@@ -189,50 +189,29 @@ func (to *BloggerTakeout) scanAlbums(ctx context.Context, filePath string) error
 			}
 			ext := strings.ToLower(path.Ext(file.Name()))
 			if ext == ".json" {
+				if file.Name() == "metadata.json" {
+					albumMetadata = path.Join(filePath, a.Name(), file.Name())
+				}
 				continue
 			}
 
 			meteDataName := file.Name() + ".json"
 			// read the metadata.json file
-			md, err := readMetadataJSON(to.vfs, path.Join(filePath, a.Name(), meteDataName))
+			md, err := resources.ReadMetadataJSON(to.vfs, path.Join(filePath, a.Name(), meteDataName))
 			if err != nil {
 				md = nil
 			}
 
 			// anything else is a resource
-			to.ressources.Add(to.vfs, a.Name(), path.Join(filePath, a.Name()), file, md)
+			to.ressources.Add(to.vfs, resources.ContainerKey(resources.BloggerSource, album), path.Join(filePath, a.Name()), file, md)
 		}
-	}
-	return nil
-}
-
-func readMetadataJSON(vfs virtualfs.FileSystem, path string) (*resources.ResourceMetadata, error) {
-	file, err := vfs.Open(path)
-	if err != nil {
-		return nil, err
-	}
-	defer file.Close()
-	var raw struct {
-		// UploadStatus        string `json:"uploadStatus"`        // "FULL_QUALITY"
-		SizeBytes           string `json:"sizeBytes"`           // "1641075"
-		Filename            string `json:"filename"`            // "IMG_0123.jpg"
-		CreationTimestampMs string `json:"creationTimestampMs"` // "1751181639509"
-		// HasOriginalBytes    string `json:"hasOriginalBytes"`    // "YES"
-		// ContentVersion      string `json:"contentVersion"`      // "124564"
-		MimeType string `json:"mimeType"` // "image/*"
-	}
 
-	err = json.NewDecoder(file).Decode(&raw)
-	if err != nil {
-		return nil, err
-	}
-
-	md := &resources.ResourceMetadata{
-		Filename: raw.Filename,
-		MimeType: raw.MimeType,
+		if albumMetadata != "" {
+			am, err := resources.ReadAlbumMetadata(to.vfs, albumMetadata, resources.ContainerKey(resources.BloggerSource, album))
+			if err == nil {
+				to.ressources.AddAlbum(am)
+			}
+		}
 	}
-	md.SizeBytes, _ = strconv.ParseInt(raw.SizeBytes, 10, 64)
-	creationTimestampMs, _ := strconv.ParseInt(raw.CreationTimestampMs, 10, 64)
-	md.CreationTimestamp = time.UnixMilli(creationTimestampMs)
-	return md, nil
+	return nil
 }
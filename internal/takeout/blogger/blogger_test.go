@@ -0,0 +1,46 @@
+package blogger
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bloggerout/internal/takeout/resources"
+	"bloggerout/internal/virtualfs"
+)
+
+func TestScanAlbums(t *testing.T) {
+	vfs, err := virtualfs.NewOSFileSystem("./data/Takeout/Blogger")
+	if err != nil {
+		t.Fatalf("failed to open test data: %v", err)
+	}
+	rs := resources.New()
+	to := New(vfs, rs)
+	err = to.scanAlbums(context.Background(), "Albums")
+	if err != nil {
+		t.Fatalf("failed to scan albums: %v", err)
+	}
+
+	albums := rs.Albums()
+	if len(albums) != 1 {
+		t.Fatalf("expected 1 album, got %d", len(albums))
+	}
+	a := albums[0]
+	if a.Title != "Blog Expercience" || a.Container != "Blogger/Blog Experience" {
+		t.Errorf("unexpected album title %q, container %q", a.Title, a.Container)
+	}
+	if a.TotalItems != 2 {
+		t.Errorf("expected 2 items, got %d", a.TotalItems)
+	}
+	if !a.Start.Equal(time.UnixMilli(1193601366000)) {
+		t.Errorf("unexpected album start: %s", a.Start)
+	}
+
+	photos := rs.SearchContainer(a.Container)
+	if len(photos) != 2 {
+		t.Fatalf("expected 2 photos in the album, got %d", len(photos))
+	}
+	if photos[0].Name() != "P1040089.jpg" || photos[1].Name() != "2011-06-13 19.44.07.jpg" {
+		t.Errorf("photos are not ordered by capture time: %s, %s", photos[0].Name(), photos[1].Name())
+	}
+}
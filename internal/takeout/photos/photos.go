@@ -0,0 +1,165 @@
+package photos
+
+import (
+	"context"
+	"io/fs"
+	"mime"
+	"path"
+	"regexp"
+	"strings"
+
+	"bloggerout/internal/takeout/resources"
+	"bloggerout/internal/virtualfs"
+
+	"github.com/simulot/TakeoutLocalization/go/localization"
+)
+
+// PhotosTakeout represents the content of a Google Photos takeout.
+// Each sub folder of the Google Photos folder is an album, or a "Photos from YYYY" folder.
+type PhotosTakeout struct {
+	vfs       virtualfs.FileSystem
+	loc       *localization.Products
+	resources *resources.Resources
+}
+
+func New(vfs virtualfs.FileSystem, resources *resources.Resources, loc *localization.Products) *PhotosTakeout {
+	return &PhotosTakeout{
+		vfs:       vfs,
+		resources: resources,
+		loc:       loc,
+	}
+}
+
+// Scan scans the Google Photos folder and registers all photos and videos into the resources
+func (to *PhotosTakeout) Scan(ctx context.Context, filePath string, globalizedPath string) (*PhotosTakeout, error) {
+	albums, err := fs.ReadDir(to.vfs, filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, a := range albums {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+		if !a.IsDir() {
+			continue
+		}
+		err = to.scanAlbum(ctx, path.Join(filePath, a.Name()), strings.TrimSuffix(a.Name(), "/"), globalizedPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return to, nil
+}
+
+// scanAlbum registers the album's photos with the metadata found in their sidecar files
+func (to *PhotosTakeout) scanAlbum(_ context.Context, albumPath string, album string, globalizedPath string) error {
+	files, err := fs.ReadDir(to.vfs, albumPath)
+	if err != nil {
+		return err
+	}
+
+	sidecars := map[string]string{} // sidecar name without .json -> sidecar name
+	media := []fs.DirEntry{}
+	albumMetadata := ""
+	for _, f := range files {
+		if f.IsDir() {
+			continue
+		}
+		name := f.Name()
+		if strings.ToLower(path.Ext(name)) != ".json" {
+			media = append(media, f)
+			continue
+		}
+		if to.isAlbumMetadata(globalizedPath, name) {
+			albumMetadata = path.Join(albumPath, name)
+			continue
+		}
+		sidecars[strings.TrimSuffix(name, path.Ext(name))] = name
+	}
+
+	for _, f := range media {
+		var md *resources.ResourceMetadata
+		if sidecar := findSidecar(f.Name(), sidecars); sidecar != "" {
+			md, err = resources.ReadMetadataJSON(to.vfs, path.Join(albumPath, sidecar))
+			if err != nil {
+				md = nil
+			}
+		}
+		if md != nil {
+			if info, err := f.Info(); err == nil {
+				md.SizeBytes = info.Size()
+				if md.CreationTimestamp.IsZero() {
+					md.CreationTimestamp = info.ModTime()
+				}
+			}
+			if md.Filename == "" {
+				md.Filename = f.Name()
+			}
+			if md.MimeType == "" {
+				md.MimeType = mime.TypeByExtension(strings.ToLower(path.Ext(f.Name())))
+			}
+		}
+		to.resources.Add(to.vfs, resources.ContainerKey(resources.PhotosSource, album), albumPath, f, md)
+	}
+
+	// Only real albums have a metadata file, not the "Photos from YYYY" folders
+	if albumMetadata != "" {
+		a, err := resources.ReadAlbumMetadata(to.vfs, albumMetadata, resources.ContainerKey(resources.PhotosSource, album))
+		if err == nil {
+			to.resources.AddAlbum(a)
+		}
+	}
+	return nil
+}
+
+// isAlbumMetadata checks if the file is the album's metadata file, whose name is localized
+func (to *PhotosTakeout) isAlbumMetadata(globalizedPath string, name string) bool {
+	if name == "metadata.json" {
+		return true
+	}
+	key, _ := to.loc.Globalize(path.Join(globalizedPath, name))
+	return key == "metadata.json"
+}
+
+// minTruncatedLength is the length under which Google Photos doesn't truncate sidecar names
+const minTruncatedLength = 46
+
+var duplicateSuffix = regexp.MustCompile(`^(.*)(\(\d+\))(\.[^.]*)$`)
+
+// findSidecar returns the name of the sidecar file of a media file.
+//
+// Google Photos takeout names the sidecar files in several ways:
+//   - IMG_0123.jpg.json
+//   - IMG_0123.jpg.supplemental-metadata.json
+//   - IMG_0123.jpg(1).json for the duplicated file IMG_0123(1).jpg
+//   - long names are truncated to 51 characters: IMG_0123.jpg.supplemental-metad.json
+func findSidecar(name string, sidecars map[string]string) string {
+	candidates := []string{name, name + ".supplemental-metadata"}
+	if m := duplicateSuffix.FindStringSubmatch(name); m != nil {
+		candidates = append(candidates,
+			m[1]+m[3]+m[2],
+			m[1]+m[3]+".supplemental-metadata"+m[2],
+		)
+	}
+	for _, c := range candidates {
+		if s, ok := sidecars[c]; ok {
+			return s
+		}
+	}
+
+	// truncated names: keep the longest prefix of the full sidecar name
+	full := name + ".supplemental-metadata"
+	best := ""
+	for stem := range sidecars {
+		if len(stem)+len(".json") >= minTruncatedLength && len(stem) > len(best) && strings.HasPrefix(full, stem) {
+			best = stem
+		}
+	}
+	if best != "" {
+		return sidecars[best]
+	}
+	return ""
+}
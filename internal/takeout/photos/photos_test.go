@@ -0,0 +1,101 @@
+package photos
+
+import (
+	"context"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"bloggerout/internal/takeout/resources"
+
+	"github.com/simulot/TakeoutLocalization/go/localization"
+)
+
+func TestFindSidecar(t *testing.T) {
+	sidecars := map[string]string{}
+	for _, s := range []string{
+		"IMG_0001.jpg.json",
+		"IMG_0002.jpg.supplemental-metadata.json",
+		"IMG_0003.jpg(1).json",
+		"IMG_0004.jpg.supplemental-metadata(2).json",
+		"Screenshot_20200101-120000_Application.jpg.supple.json",
+	} {
+		sidecars[s[:len(s)-len(".json")]] = s
+	}
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"IMG_0001.jpg", "IMG_0001.jpg.json"},
+		{"IMG_0002.jpg", "IMG_0002.jpg.supplemental-metadata.json"},
+		{"IMG_0003(1).jpg", "IMG_0003.jpg(1).json"},
+		{"IMG_0004(2).jpg", "IMG_0004.jpg.supplemental-metadata(2).json"},
+		{"Screenshot_20200101-120000_Application.jpg", "Screenshot_20200101-120000_Application.jpg.supple.json"},
+		{"IMG_0005.jpg", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := findSidecar(tc.name, sidecars)
+			if got != tc.expected {
+				t.Errorf("findSidecar(%q) = %q; want %q", tc.name, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestScan(t *testing.T) {
+	vfs := fstest.MapFS{
+		"Takeout/Google Photos/Holidays/metadata.json": &fstest.MapFile{Data: []byte(`{"title": "Holidays"}`)},
+		"Takeout/Google Photos/Holidays/IMG_0001.jpg":  &fstest.MapFile{Data: []byte("jpeg")},
+		"Takeout/Google Photos/Holidays/IMG_0001.jpg.json": &fstest.MapFile{Data: []byte(`{
+			"title": "IMG_0001.jpg",
+			"description": "At the beach",
+			"creationTime": {"timestamp": "1309102873"},
+			"photoTakenTime": {"timestamp": "1309000000"},
+			"geoData": {"latitude": 48.85, "longitude": 2.35, "altitude": 35.0},
+			"people": [{"name": "Paul"}, {"name": "Marie"}]
+		}`)},
+		"Takeout/Google Photos/Holidays/IMG_0002(1).jpg":      &fstest.MapFile{Data: []byte("jpeg")},
+		"Takeout/Google Photos/Holidays/IMG_0002.jpg(1).json": &fstest.MapFile{Data: []byte(`{"title": "IMG_0002.jpg"}`)},
+	}
+
+	rs := resources.New()
+	loc := localization.GetDefaultLocalizations()
+	_, err := New(vfs, rs, &loc).Scan(context.Background(), "Takeout/Google Photos", "Google Photos")
+	if err != nil {
+		t.Fatalf("Scan failed: %v", err)
+	}
+
+	l := rs.SearchByBase("IMG_0001.jpg")
+	if len(l) != 1 {
+		t.Fatalf("expected IMG_0001.jpg to be registered once, got %d", len(l))
+	}
+	md := l[0].Metadata()
+	if md.Description != "At the beach" {
+		t.Errorf("expected description %q, got %q", "At the beach", md.Description)
+	}
+	if !md.PhotoTakenTime.Equal(time.Unix(1309000000, 0)) {
+		t.Errorf("unexpected photo taken time: %s", md.PhotoTakenTime)
+	}
+	if md.GeoData.Latitude != 48.85 || md.GeoData.Longitude != 2.35 {
+		t.Errorf("unexpected geo data: %+v", md.GeoData)
+	}
+	if len(md.People) != 2 || md.People[0] != "Paul" || md.People[1] != "Marie" {
+		t.Errorf("unexpected people: %v", md.People)
+	}
+	if md.MimeType != "image/jpeg" {
+		t.Errorf("expected mime type image/jpeg, got %q", md.MimeType)
+	}
+	if l[0].Container() != "Google Photos/Holidays" {
+		t.Errorf("expected container Google Photos/Holidays, got %q", l[0].Container())
+	}
+
+	// the duplicated file is found by its original name
+	if l := rs.SearchByBase("IMG_0002.jpg"); len(l) != 1 {
+		t.Errorf("expected IMG_0002(1).jpg to be found as IMG_0002.jpg")
+	}
+	if r := rs.SearchInContainer("Google Photos/Holidays", "metadata.json"); r != nil {
+		t.Errorf("album metadata must not be registered as a resource")
+	}
+}
@@ -0,0 +1,188 @@
+package resources
+
+import (
+	"encoding/json"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"bloggerout/internal/virtualfs"
+)
+
+// Album represents an album found in the Blogger Albums or Google Photos takeouts.
+// The album's photos are the resources of the album's container.
+type Album struct {
+	Title       string    // title of the album, from metadata.json
+	Description string    // description of the album
+	Container   string    // source and folder of the album, used as container for its resources
+	Start       time.Time // capture time of the first photo
+	End         time.Time // capture time of the last photo
+	Creation    time.Time // creation date of the album
+	TotalItems  int       // number of items announced by the metadata
+}
+
+// Date returns the best known date of the album
+func (a Album) Date() time.Time {
+	if !a.Start.IsZero() {
+		return a.Start
+	}
+	return a.Creation
+}
+
+// AddAlbum registers an album. The album's resources must be added before
+// to complete the time range and the number of items when the metadata lacks them.
+func (rs *Resources) AddAlbum(a *Album) {
+	l := rs.SearchContainer(a.Container)
+	if len(l) > 0 {
+		if a.Start.IsZero() {
+			a.Start = l[0].CaptureTime()
+		}
+		if a.End.IsZero() {
+			a.End = l[len(l)-1].CaptureTime()
+		}
+	}
+	if a.TotalItems == 0 {
+		a.TotalItems = len(l)
+	}
+	rs.albums[a.Container] = a
+}
+
+// Albums returns all albums sorted by date
+func (rs *Resources) Albums() []*Album {
+	l := make([]*Album, 0, len(rs.albums))
+	for _, a := range rs.albums {
+		l = append(l, a)
+	}
+	slices.SortFunc(l, func(a, b *Album) int {
+		if c := a.Date().Compare(b.Date()); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Container, b.Container)
+	})
+	return l
+}
+
+// SearchAlbum returns the album stored in the container
+func (rs *Resources) SearchAlbum(container string) *Album {
+	return rs.albums[container]
+}
+
+// ReadAlbumMetadata reads the album's metadata.json file.
+// Both the Blogger albums and the Google Photos flavors are understood.
+func ReadAlbumMetadata(vfs virtualfs.FileSystem, path string, container string) (*Album, error) {
+	file, err := vfs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var raw struct {
+		Title       string `json:"title"`
+		Description string `json:"description"`
+		TotalItems  string `json:"totalItems"` // Blogger albums: "2"
+		TimeInfo    struct {
+			StartMs    string `json:"startMs"`    // Blogger albums: "1193601366000"
+			EndMs      string `json:"endMs"`      // Blogger albums: "1309102873000"
+			CreationMs string `json:"creationMs"` // Blogger albums: "1193565916000"
+		} `json:"timeInfo"`
+		Date struct {
+			Timestamp string `json:"timestamp"` // Google Photos: "1309102873"
+		} `json:"date"`
+	}
+	err = json.NewDecoder(file).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Album{
+		Title:       raw.Title,
+		Description: raw.Description,
+		Container:   container,
+	}
+	if a.Title == "" {
+		a.Title = ContainerFolder(container)
+	}
+	a.TotalItems, _ = strconv.Atoi(raw.TotalItems)
+	a.Start = parseMs(raw.TimeInfo.StartMs)
+	a.End = parseMs(raw.TimeInfo.EndMs)
+	a.Creation = parseMs(raw.TimeInfo.CreationMs)
+	if a.Creation.IsZero() && raw.Date.Timestamp != "" {
+		ts, _ := strconv.ParseInt(raw.Date.Timestamp, 10, 64)
+		a.Creation = time.Unix(ts, 0)
+	}
+	return a, nil
+}
+
+func parseMs(s string) time.Time {
+	if s == "" {
+		return time.Time{}
+	}
+	ms, _ := strconv.ParseInt(s, 10, 64)
+	return time.UnixMilli(ms)
+}
+
+// SearchAlbumByName returns the album whose title or folder matches the name.
+// The comparison ignores case, accents, spaces and punctuation, so the
+// Picasa album name "VacancesEte2008" matches the title "Vacances été 2008".
+func (rs *Resources) SearchAlbumByName(name string) *Album {
+	n := NormalizeName(name)
+	if n == "" {
+		return nil
+	}
+	for _, a := range rs.Albums() {
+		if NormalizeName(a.Title) == n || NormalizeName(ContainerFolder(a.Container)) == n {
+			return a
+		}
+	}
+	return nil
+}
+
+// SearchAlbumByDate returns the album whose last photo is the closest to the date,
+// within the given tolerance. The albums rejected by skip, when not nil, are ignored.
+func (rs *Resources) SearchAlbumByDate(date time.Time, tolerance time.Duration, skip func(*Album) bool) *Album {
+	var best *Album
+	var bestDelta time.Duration
+	for _, a := range rs.Albums() {
+		if skip != nil && skip(a) {
+			continue
+		}
+		end := a.End
+		if end.IsZero() {
+			end = a.Date()
+		}
+		if end.IsZero() {
+			continue
+		}
+		delta := date.Sub(end).Abs()
+		if delta > tolerance {
+			continue
+		}
+		if best == nil || delta < bestDelta {
+			best, bestDelta = a, delta
+		}
+	}
+	return best
+}
+
+var accentFolder = strings.NewReplacer(
+	"à", "a", "â", "a", "ä", "a", "á", "a", "ã", "a", "å", "a",
+	"ç", "c",
+	"é", "e", "è", "e", "ê", "e", "ë", "e",
+	"î", "i", "ï", "i", "í", "i", "ì", "i",
+	"ô", "o", "ö", "o", "ó", "o", "ò", "o", "õ", "o",
+	"ù", "u", "û", "u", "ü", "u", "ú", "u",
+	"ÿ", "y", "ñ", "n", "œ", "oe", "æ", "ae", "ß", "ss",
+)
+
+// NormalizeName keeps only the lower case letters and digits of the name, without accents
+func NormalizeName(name string) string {
+	name = accentFolder.Replace(strings.ToLower(name))
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, name)
+}
@@ -0,0 +1,45 @@
+package resources
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSearchAlbum(t *testing.T) {
+	rs := New()
+	rs.AddAlbum(&Album{Title: "Vacances été 2008", Container: "Vacances 2008", End: time.Date(2008, 8, 20, 0, 0, 0, 0, time.UTC)})
+	rs.AddAlbum(&Album{Title: "Noël", Container: "Noel", End: time.Date(2008, 12, 25, 0, 0, 0, 0, time.UTC)})
+
+	testCases := []struct {
+		name     string
+		expected string
+	}{
+		{"VacancesEte2008", "Vacances 2008"},
+		{"vacances-2008", "Vacances 2008"},
+		{"NOEL", "Noel"},
+		{"Anniversaire", ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			a := rs.SearchAlbumByName(tc.name)
+			got := ""
+			if a != nil {
+				got = a.Container
+			}
+			if got != tc.expected {
+				t.Errorf("SearchAlbumByName(%q) = %q; want %q", tc.name, got, tc.expected)
+			}
+		})
+	}
+
+	if a := rs.SearchAlbumByDate(time.Date(2008, 8, 25, 0, 0, 0, 0, time.UTC), 15*24*time.Hour, nil); a == nil || a.Container != "Vacances 2008" {
+		t.Errorf("expected to find the album by date")
+	}
+	if a := rs.SearchAlbumByDate(time.Date(2008, 10, 1, 0, 0, 0, 0, time.UTC), 15*24*time.Hour, nil); a != nil {
+		t.Errorf("expected no album for a date far from any album, got %q", a.Container)
+	}
+	skip := func(a *Album) bool { return a.Container == "Vacances 2008" }
+	if a := rs.SearchAlbumByDate(time.Date(2008, 8, 25, 0, 0, 0, 0, time.UTC), 15*24*time.Hour, skip); a != nil {
+		t.Errorf("expected the skipped album to be ignored, got %q", a.Container)
+	}
+}
@@ -0,0 +1,96 @@
+package resources
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"bloggerout/internal/virtualfs"
+)
+
+// GeoData is the location of a photo as recorded in the takeout's sidecar files
+type GeoData struct {
+	Latitude  float64
+	Longitude float64
+	Altitude  float64
+}
+
+// IsZero reports whether the location is unknown.
+// Google Photos writes 0.0 coordinates when the photo has no location.
+func (g GeoData) IsZero() bool {
+	return g.Latitude == 0 && g.Longitude == 0
+}
+
+// ReadMetadataJSON reads a takeout's sidecar file (IMG_0123.jpg.json).
+// Both the Blogger albums and the Google Photos flavors are understood.
+func ReadMetadataJSON(vfs virtualfs.FileSystem, path string) (*ResourceMetadata, error) {
+	file, err := vfs.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	type timestamp struct {
+		Timestamp string `json:"timestamp"` // "1309102873" seconds since epoch
+	}
+	type geoData struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+		Altitude  float64 `json:"altitude"`
+	}
+	var raw struct {
+		// Blogger albums
+		SizeBytes           string `json:"sizeBytes"`           // "1641075"
+		Filename            string `json:"filename"`            // "IMG_0123.jpg"
+		CreationTimestampMs string `json:"creationTimestampMs"` // "1751181639509"
+		MimeType            string `json:"mimeType"`            // "image/*"
+
+		// Google Photos
+		Title          string    `json:"title"`          // "IMG_0123.jpg"
+		Description    string    `json:"description"`    // "Grandpa's birthday"
+		CreationTime   timestamp `json:"creationTime"`   // upload time
+		PhotoTakenTime timestamp `json:"photoTakenTime"` // capture time
+		GeoData        geoData   `json:"geoData"`
+		GeoDataExif    geoData   `json:"geoDataExif"`
+		People         []struct {
+			Name string `json:"name"`
+		} `json:"people"`
+	}
+
+	err = json.NewDecoder(file).Decode(&raw)
+	if err != nil {
+		return nil, err
+	}
+
+	md := &ResourceMetadata{
+		Filename:    raw.Filename,
+		MimeType:    raw.MimeType,
+		Title:       raw.Title,
+		Description: raw.Description,
+		GeoData:     GeoData(raw.GeoData),
+	}
+	if md.Filename == "" {
+		md.Filename = raw.Title
+	}
+	if md.GeoData.IsZero() {
+		md.GeoData = GeoData(raw.GeoDataExif)
+	}
+	for _, p := range raw.People {
+		if p.Name != "" {
+			md.People = append(md.People, p.Name)
+		}
+	}
+	md.SizeBytes, _ = strconv.ParseInt(raw.SizeBytes, 10, 64)
+	if raw.CreationTimestampMs != "" {
+		creationTimestampMs, _ := strconv.ParseInt(raw.CreationTimestampMs, 10, 64)
+		md.CreationTimestamp = time.UnixMilli(creationTimestampMs)
+	} else if raw.CreationTime.Timestamp != "" {
+		creationTimestamp, _ := strconv.ParseInt(raw.CreationTime.Timestamp, 10, 64)
+		md.CreationTimestamp = time.Unix(creationTimestamp, 0)
+	}
+	if raw.PhotoTakenTime.Timestamp != "" {
+		photoTakenTime, _ := strconv.ParseInt(raw.PhotoTakenTime.Timestamp, 10, 64)
+		md.PhotoTakenTime = time.Unix(photoTakenTime, 0)
+	}
+	return md, nil
+}
@@ -2,8 +2,12 @@ package resources
 
 import (
 	"io/fs"
+	"maps"
 	"path"
+	"slices"
 	"sort"
+	"strings"
+	"sync"
 	"time"
 
 	"bloggerout/internal/virtualfs"
@@ -14,6 +18,11 @@ type ResourceMetadata struct {
 	Filename          string    // "IMG_0123.jpg"
 	CreationTimestamp time.Time // "1751181639509" --> to time.Time
 	MimeType          string    // "image/*"
+	Title             string    // original file name or title given in Google Photos
+	Description       string    // description given in Google Photos
+	PhotoTakenTime    time.Time // capture time
+	GeoData           GeoData   // location of the photo
+	People            []string  // names of the people tagged on the photo
 }
 
 type Resource struct {
@@ -44,10 +53,55 @@ func (r Resource) SizeBytes() int64 {
 	return r.metadata.SizeBytes
 }
 
+func (r Resource) Name() string {
+	return r.dirEntry.Name()
+}
+
+// CaptureTime returns the time the photo was taken when known, the creation time otherwise
+func (r Resource) CaptureTime() time.Time {
+	if !r.metadata.PhotoTakenTime.IsZero() {
+		return r.metadata.PhotoTakenTime
+	}
+	return r.metadata.CreationTimestamp
+}
+
+func (r Resource) Metadata() ResourceMetadata {
+	return r.metadata
+}
+
+func (r Resource) Container() string {
+	return r.container
+}
+
+// Sources of the resources, prefixing their container names:
+// a Blogger album and a Google Photos folder of the same name are different containers.
+const (
+	BloggerSource = "Blogger"
+	PhotosSource  = "Google Photos"
+	YouTubeSource = "YouTube"
+)
+
+// ContainerKey returns the container name of the source's folder
+func ContainerKey(source string, folder string) string {
+	return source + "/" + folder
+}
+
+// ContainerFolder returns the folder of the container, without its source
+func ContainerFolder(container string) string {
+	if _, folder, ok := strings.Cut(container, "/"); ok {
+		return folder
+	}
+	return container
+}
+
 type Resources struct {
 	byPath      map[string]*Resource   // by path of the resource
 	byBase      map[string][]*Resource // by base name of the resource
-	byContainer map[string][]*Resource // by container name of the resource (ex Album name, Blog name, YT channel)
+	byContainer map[string][]*Resource // by container name of the resource (ex Blogger/Album name, Blogger/Blog name, YouTube/channel ID)
+	albums      map[string]*Album      // by container name of the album
+
+	mu   sync.Mutex
+	used map[*Resource]int // number of references to the resource in the converted posts
 }
 
 func New() *Resources {
@@ -55,6 +109,8 @@ func New() *Resources {
 		byPath:      make(map[string]*Resource),
 		byBase:      make(map[string][]*Resource),
 		byContainer: make(map[string][]*Resource),
+		albums:      make(map[string]*Album),
+		used:        make(map[*Resource]int),
 	}
 }
 
@@ -72,6 +128,10 @@ func (rs *Resources) Add(vfs virtualfs.FileSystem, container string, filePath st
 	}
 	l := rs.byBase[base]
 	rs.byBase[base] = append(l, r)
+	if title := r.metadata.Title; title != "" && title != base {
+		// Google Photos renames duplicated files, the original name is kept in the title
+		rs.byBase[title] = append(rs.byBase[title], r)
+	}
 	l = rs.byContainer[container]
 	rs.byContainer[container] = append(l, r)
 	rs.byPath[path.Join(filePath, base)] = r
@@ -106,6 +166,86 @@ func (rs *Resources) SearchByBaseAndDate(base string, date time.Time) *Resource
 	return l[0]
 }
 
+// SearchVideosByDate returns the videos captured within the tolerance of the date,
+// the closest first, and the biggest file first when captured at the same time
+func (rs *Resources) SearchVideosByDate(date time.Time, tolerance time.Duration) []*Resource {
+	l := []*Resource{}
+	for _, r := range rs.byPath {
+		if IsVideo(r.Name()) && date.Sub(r.CaptureTime()).Abs() <= tolerance {
+			l = append(l, r)
+		}
+	}
+	sort.Slice(l, func(i, j int) bool {
+		di := date.Sub(l[i].CaptureTime()).Abs()
+		dj := date.Sub(l[j].CaptureTime()).Abs()
+		if di != dj {
+			return di < dj
+		}
+		if l[i].SizeBytes() != l[j].SizeBytes() {
+			return l[i].SizeBytes() > l[j].SizeBytes()
+		}
+		return l[i].path+l[i].Name() < l[j].path+l[j].Name()
+	})
+	return l
+}
+
+// Containers returns the names of the containers, sorted
+func (rs *Resources) Containers() []string {
+	return slices.Sorted(maps.Keys(rs.byContainer))
+}
+
+// SearchContainer returns the resources of the container ordered by capture time
+func (rs *Resources) SearchContainer(container string) []*Resource {
+	l := make([]*Resource, len(rs.byContainer[container]))
+	copy(l, rs.byContainer[container])
+	sort.SliceStable(l, func(i, j int) bool {
+		return l[i].CaptureTime().Before(l[j].CaptureTime())
+	})
+	return l
+}
+
 func (rs *Resources) SearchByPath(filePath string) *Resource {
 	return rs.byPath[filePath]
 }
+
+// Use records a reference to the resource in a converted post.
+// It is safe for concurrent use.
+func (rs *Resources) Use(r *Resource) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	rs.used[r]++
+}
+
+// Orphans returns the resources never referenced by a converted post,
+// grouped by container and ordered by capture time
+func (rs *Resources) Orphans() map[string][]*Resource {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	orphans := map[string][]*Resource{}
+	for container := range rs.byContainer {
+		for _, r := range rs.SearchContainer(container) {
+			if rs.used[r] == 0 && (IsImage(r.Name()) || IsVideo(r.Name())) {
+				orphans[container] = append(orphans[container], r)
+			}
+		}
+	}
+	return orphans
+}
+
+// IsImage checks the file extension against the image formats found in takeouts
+func IsImage(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".jpg", ".jpeg", ".png", ".gif", ".webp", ".heic", ".bmp", ".tif", ".tiff":
+		return true
+	}
+	return false
+}
+
+// IsVideo checks the file extension against the video formats found in takeouts
+func IsVideo(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".mp4", ".m4v", ".mov", ".avi", ".3gp", ".mkv", ".mpg", ".mpeg", ".webm", ".wmv":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package resources
+
+import (
+	"io/fs"
+	"slices"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestOrphans(t *testing.T) {
+	vfs := fstest.MapFS{
+		"Blog/used.jpg":     &fstest.MapFile{Data: []byte("jpeg")},
+		"Blog/orphan.jpg":   &fstest.MapFile{Data: []byte("jpeg")},
+		"Blog/settings.csv": &fstest.MapFile{Data: []byte("csv")},
+		"Album/movie.mp4":   &fstest.MapFile{Data: []byte("mp4")},
+	}
+
+	rs := New()
+	for _, dir := range []string{"Blog", "Album"} {
+		entries, err := fs.ReadDir(vfs, dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, e := range entries {
+			rs.Add(vfs, dir, dir, e, nil)
+		}
+	}
+	rs.Use(rs.SearchInContainer("Blog", "used.jpg"))
+
+	orphans := rs.Orphans()
+	if len(orphans["Blog"]) != 1 || orphans["Blog"][0].Name() != "orphan.jpg" {
+		t.Errorf("expected orphan.jpg to be the only orphan of Blog, got %v", orphans["Blog"])
+	}
+	if len(orphans["Album"]) != 1 || orphans["Album"][0].Name() != "movie.mp4" {
+		t.Errorf("expected movie.mp4 to be an orphan of Album, got %v", orphans["Album"])
+	}
+}
+
+func TestSearchVideosByDate(t *testing.T) {
+	date := time.Date(2012, 5, 1, 12, 0, 0, 0, time.UTC)
+	vfs := fstest.MapFS{
+		"Blog/small.mp4": &fstest.MapFile{},
+		"Blog/big.mp4":   &fstest.MapFile{},
+		"Blog/later.mov": &fstest.MapFile{},
+		"Blog/photo.jpg": &fstest.MapFile{},
+		"Blog/old.mp4":   &fstest.MapFile{},
+	}
+	metadata := map[string]ResourceMetadata{
+		"small.mp4": {CreationTimestamp: date.Add(-time.Hour), SizeBytes: 10},
+		"big.mp4":   {CreationTimestamp: date.Add(-time.Hour), SizeBytes: 1000},
+		"later.mov": {CreationTimestamp: date.Add(2 * time.Hour), SizeBytes: 10},
+		"photo.jpg": {CreationTimestamp: date},
+		"old.mp4":   {CreationTimestamp: date.AddDate(-1, 0, 0)},
+	}
+	rs := New()
+	entries, err := fs.ReadDir(vfs, "Blog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, e := range entries {
+		md := metadata[e.Name()]
+		rs.Add(vfs, "Blog", "Blog", e, &md)
+	}
+
+	got := []string{}
+	for _, r := range rs.SearchVideosByDate(date, 24*time.Hour) {
+		got = append(got, r.Name())
+	}
+	if want := []string{"big.mp4", "small.mp4", "later.mov"}; !slices.Equal(got, want) {
+		t.Errorf("SearchVideosByDate() = %v; want %v", got, want)
+	}
+}
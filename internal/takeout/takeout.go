@@ -10,6 +10,7 @@ import (
 	"strings"
 
 	"bloggerout/internal/takeout/blogger"
+	"bloggerout/internal/takeout/photos"
 	"bloggerout/internal/takeout/resources"
 	"bloggerout/internal/takeout/youtube"
 	"bloggerout/internal/virtualfs"
@@ -18,13 +19,14 @@ import (
 )
 
 // Takeout represents the main structure for handling data exported from various platforms.
-// It includes localization data, blogger-specific data, YouTube-specific data, and a virtual file system.
+// It includes localization data, blogger-specific data, YouTube-specific data, Google Photos data, and a virtual file system.
 type Takeout struct {
 	vfs          virtualfs.FileSystem
 	Localization localization.Products   // Localization data for the takeout
 	Resources    *resources.Resources    // Photos and Videos contained in the takeout
 	Blogger      *blogger.BloggerTakeout // The blogger data contained in the takeout
 	YouTube      *youtube.YouTubeTakeout // The YouTube data contained in the takeout
+	Photos       *photos.PhotosTakeout   // The Google Photos data contained in the takeout
 }
 
 // ReadTakeout read the Blogger Takeout file or folder.
@@ -113,6 +115,8 @@ func (to *Takeout) processDirectory(ctx context.Context) (*Takeout, error) {
 						to.Blogger, err = blogger.New(to.vfs, to.Resources).Scan(ctx, filePath)
 					case "YouTube and YouTube Music":
 						to.YouTube, err = youtube.New(to.vfs, to.Resources, &to.Localization).Scan(ctx, filePath, key)
+					case "Google Photos":
+						to.Photos, err = photos.New(to.vfs, to.Resources, &to.Localization).Scan(ctx, filePath, key)
 					default:
 						return nil
 					}
@@ -0,0 +1,206 @@
+package youtube
+
+import (
+	"io/fs"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"bloggerout/internal/takeout/resources"
+)
+
+// minTruncatedTitle is the length of a normalized file name worth a prefix match:
+// the takeout truncates the long titles when naming the video files.
+const minTruncatedTitle = 12
+
+// duplicateSuffix matches the counter added by the takeout to files having the same title: "My video(1)"
+var duplicateSuffix = regexp.MustCompile(`\s*\(\d+\)$`)
+
+// titleKey gives the key used to match a video file name or a video title
+func titleKey(title string) string {
+	return resources.NormalizeName(title)
+}
+
+// fileTitleKey gives the key of a video file name, without the extension and the duplicate counter
+func fileTitleKey(name string) string {
+	title := strings.TrimSuffix(name, path.Ext(name))
+	return titleKey(duplicateSuffix.ReplaceAllString(title, ""))
+}
+
+// videoFile is a file of the takeout's videos folder
+type videoFile struct {
+	dir      string
+	entry    fs.DirEntry
+	key      string
+	modTime  time.Time
+	duration time.Duration // 0 when unknown
+	probed   bool          // the duration has been read
+}
+
+// videoMatch is a possible association between a file and a metadata row
+type videoMatch struct {
+	file      *videoFile
+	video     *Video
+	truncated bool          // the file name is a prefix of the title
+	duration  time.Duration // difference of durations, math.MaxInt64 when unknown
+	created   time.Duration // difference between the creation time and the file time, math.MaxInt64 when unknown
+}
+
+// matchVideos links the video files to the metadata rows.
+//
+// Titles are compared once normalized, without the counter added to the duplicated names.
+// When the file name is truncated, it's compared to the beginning of the titles, the longest prefix first.
+// When several files or rows compete, the closest durations, then the closest creation times win.
+// The remaining files are recorded as unmatched.
+func (to *YouTubeTakeout) matchVideos(dir string, entries []fs.DirEntry) {
+	files := []*videoFile{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		f := &videoFile{dir: dir, entry: e, key: fileTitleKey(e.Name())}
+		if info, err := e.Info(); err == nil {
+			f.modTime = info.ModTime()
+		}
+		files = append(files, f)
+	}
+
+	// collect the candidates
+	matches := []*videoMatch{}
+	countByFile := map[*videoFile]int{}
+	countByVideo := map[*Video]int{}
+	for _, f := range files {
+		candidates := []*videoMatch{}
+		for _, v := range to.videosByTitle[f.key] {
+			if v.Resource == nil {
+				candidates = append(candidates, &videoMatch{file: f, video: v})
+			}
+		}
+		if len(candidates) == 0 && len(f.key) >= minTruncatedTitle {
+			for _, v := range to.videos {
+				if v.Resource == nil && strings.HasPrefix(titleKey(v.Title), f.key) {
+					candidates = append(candidates, &videoMatch{file: f, video: v, truncated: true})
+				}
+			}
+		}
+		for _, m := range candidates {
+			countByFile[f]++
+			countByVideo[m.video]++
+		}
+		matches = append(matches, candidates...)
+	}
+
+	// compute the tie-breakers of the ambiguous matches only, reading the duration can be expensive
+	for _, m := range matches {
+		m.duration, m.created = math.MaxInt64, math.MaxInt64
+		if countByFile[m.file] < 2 && countByVideo[m.video] < 2 {
+			continue
+		}
+		if m.video.Duration > 0 {
+			if !m.file.probed {
+				m.file.duration = to.readDuration(m.file)
+				m.file.probed = true
+			}
+			if m.file.duration > 0 {
+				m.duration = (m.file.duration - m.video.Duration).Abs()
+			}
+		}
+		if !m.video.Created.IsZero() && !m.file.modTime.IsZero() {
+			m.created = m.file.modTime.Sub(m.video.Created).Abs()
+		}
+	}
+
+	order := map[*Video]int{}
+	for i, v := range to.videos {
+		order[v] = i
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.truncated != b.truncated {
+			return !a.truncated
+		}
+		if a.truncated && len(a.file.key) != len(b.file.key) {
+			return len(a.file.key) > len(b.file.key) // the longest prefix is the most specific
+		}
+		if a.duration != b.duration {
+			return a.duration < b.duration
+		}
+		if a.created != b.created {
+			return a.created < b.created
+		}
+		return order[a.video] < order[b.video]
+	})
+
+	matched := map[*videoFile]bool{}
+	for _, m := range matches {
+		if matched[m.file] || m.video.Resource != nil {
+			continue
+		}
+		matched[m.file] = true
+		m.video.FileName = path.Join(m.file.dir, m.file.entry.Name())
+		m.video.Resource = to.resources.Add(to.vfs, resources.ContainerKey(resources.YouTubeSource, m.video.ChannelID), m.file.dir, m.file.entry, nil)
+	}
+	for _, f := range files {
+		if !matched[f] {
+			to.unmatchedFiles = append(to.unmatchedFiles, path.Join(f.dir, f.entry.Name()))
+		}
+	}
+}
+
+// matchThumbnails links the images found in the video folders to the videos.
+// The image is named after the video ID, the video file or the video title.
+func (to *YouTubeTakeout) matchThumbnails() {
+	byFile := map[string]*Video{}
+	for _, v := range to.videos {
+		if v.FileName != "" {
+			name := path.Base(v.FileName)
+			byFile[strings.TrimSuffix(name, path.Ext(name))] = v
+		}
+	}
+	for _, t := range to.thumbnails {
+		name := path.Base(t)
+		base := strings.TrimSuffix(name, path.Ext(name))
+		v := to.videosByID[base]
+		if v == nil {
+			v = byFile[base]
+		}
+		if v == nil {
+			for _, c := range to.videosByTitle[fileTitleKey(name)] {
+				if c.Thumbnail == "" {
+					v = c
+					break
+				}
+			}
+		}
+		if v != nil && v.Thumbnail == "" {
+			v.Thumbnail = t
+		}
+	}
+}
+
+// readDuration gets the duration of the video file, 0 when unknown
+func (to *YouTubeTakeout) readDuration(f *videoFile) time.Duration {
+	r, err := to.vfs.Open(path.Join(f.dir, f.entry.Name()))
+	if err != nil {
+		return 0
+	}
+	defer r.Close()
+	d, err := mp4Duration(r)
+	if err != nil {
+		return 0
+	}
+	return d
+}
+
+// Unmatched returns the video files without metadata, and the metadata rows without video file
+func (to *YouTubeTakeout) Unmatched() (files []string, videos []*Video) {
+	for _, v := range to.videos {
+		if v.Resource == nil {
+			videos = append(videos, v)
+		}
+	}
+	return to.unmatchedFiles, videos
+}
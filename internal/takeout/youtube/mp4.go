@@ -0,0 +1,116 @@
+package youtube
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+const (
+	maxMP4Skip = 4 << 20  // bytes skipped to reach the moov box on a non seekable file
+	maxMoovBox = 32 << 20 // size of the moov box read in memory
+)
+
+var errNoMP4Duration = errors.New("no duration found in the video file")
+
+// mp4Duration reads the duration from the mvhd box of a MP4 / MOV file.
+// Files read from a zip aren't seekable: the duration is available only when the moov box
+// is placed near the beginning of the file.
+func mp4Duration(r io.Reader) (time.Duration, error) {
+	var hdr [16]byte
+	for {
+		if _, err := io.ReadFull(r, hdr[:8]); err != nil {
+			return 0, errNoMP4Duration
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		hdrSize := int64(8)
+		switch size {
+		case 0: // the box extends to the end of the file
+			if typ != "moov" {
+				return 0, errNoMP4Duration
+			}
+			size = maxMoovBox
+		case 1:
+			if _, err := io.ReadFull(r, hdr[8:16]); err != nil {
+				return 0, errNoMP4Duration
+			}
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			hdrSize = 16
+		}
+		if size < hdrSize {
+			return 0, fmt.Errorf("invalid MP4 box size %d", size)
+		}
+		if typ == "moov" {
+			if size-hdrSize > maxMoovBox {
+				return 0, errNoMP4Duration
+			}
+			box := make([]byte, size-hdrSize)
+			n, err := io.ReadFull(r, box)
+			if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+				return 0, err
+			}
+			return mvhdDuration(box[:n])
+		}
+		if err := skip(r, size-hdrSize); err != nil {
+			return 0, err
+		}
+	}
+}
+
+// skip advances the reader, seeking when possible
+func skip(r io.Reader, n int64) error {
+	if s, ok := r.(io.Seeker); ok {
+		_, err := s.Seek(n, io.SeekCurrent)
+		return err
+	}
+	if n > maxMP4Skip {
+		return errNoMP4Duration
+	}
+	_, err := io.CopyN(io.Discard, r, n)
+	if err != nil {
+		return errNoMP4Duration
+	}
+	return nil
+}
+
+// mvhdDuration searches the mvhd box in the content of the moov box
+func mvhdDuration(moov []byte) (time.Duration, error) {
+	for len(moov) >= 8 {
+		size := int(binary.BigEndian.Uint32(moov[:4]))
+		if size < 8 || size > len(moov) {
+			break
+		}
+		if string(moov[4:8]) == "mvhd" {
+			b := moov[8:size]
+			if len(b) < 1 {
+				break
+			}
+			var scale, duration uint64
+			switch b[0] {
+			case 0:
+				if len(b) < 20 {
+					return 0, errNoMP4Duration
+				}
+				scale = uint64(binary.BigEndian.Uint32(b[12:16]))
+				duration = uint64(binary.BigEndian.Uint32(b[16:20]))
+			case 1:
+				if len(b) < 32 {
+					return 0, errNoMP4Duration
+				}
+				scale = uint64(binary.BigEndian.Uint32(b[20:24]))
+				duration = binary.BigEndian.Uint64(b[24:32])
+			default:
+				return 0, errNoMP4Duration
+			}
+			if scale == 0 {
+				return 0, errNoMP4Duration
+			}
+			return time.Duration(float64(duration) / float64(scale) * float64(time.Second)), nil
+		}
+		moov = moov[size:]
+	}
+	return 0, errNoMP4Duration
+}
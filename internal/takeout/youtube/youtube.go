@@ -4,8 +4,12 @@ import (
 	"context"
 	"fmt"
 	"io/fs"
+	"maps"
 	"path"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
 
 	"bloggerout/internal/takeout/resources"
 	"bloggerout/internal/virtualfs"
@@ -16,11 +20,18 @@ import (
 // https://developers.google.com/data-portability/schema-reference/youtube
 
 type Video struct {
-	ID        string
-	ChannelID string
-	Title     string              // Title as found in the metadata
-	Resource  *resources.Resource // The link to the resource in the virtual file system
-	FileName  string
+	ID          string
+	ChannelID   string
+	Title       string              // Title as found in the metadata
+	Resource    *resources.Resource // The link to the resource in the virtual file system
+	FileName    string
+	Description string        // Description as found in the metadata
+	Privacy     string        // Public, Unlisted, Private
+	Duration    time.Duration // Approximate duration given by the metadata, 0 when unknown
+	Created     time.Time     // Creation time given by the metadata
+	Published   time.Time     // Publication time given by the metadata, zero when not published
+	Thumbnail   string        // Path of the thumbnail image in the virtual file system, empty when not found
+	Tags        []string      // Tags given in the video tags.csv file
 }
 
 type Channel struct {
@@ -29,13 +40,25 @@ type Channel struct {
 	Videos map[string]*Video // Map of video IDs to Video structs
 }
 
+// Playlist lists videos in the order given by the playlist's CSV file
+type Playlist struct {
+	ID         string
+	Title      string
+	Visibility string   // Public, Unlisted, Private
+	VideoIDs   []string // IDs of the playlist's videos, possibly missing in the takeout
+}
+
 type YouTubeTakeout struct {
-	vfs           virtualfs.FileSystem
-	loc           *localization.Products
-	resources     *resources.Resources
-	channels      map[string]Channel
-	videosByID    map[string]*Video // Map of video IDs to Video structs
-	videosByTitle map[string]*Video // Map of video titles to Video structs
+	vfs            virtualfs.FileSystem
+	loc            *localization.Products
+	resources      *resources.Resources
+	channels       map[string]Channel
+	videos         []*Video             // Videos in the metadata order
+	videosByID     map[string]*Video    // Map of video IDs to Video structs
+	videosByTitle  map[string][]*Video  // Map of normalized video titles to Video structs
+	unmatchedFiles []string             // Video files without metadata
+	thumbnails     []string             // Image files found in the video folders
+	playlists      map[string]*Playlist // Map of playlist IDs to Playlist structs
 }
 
 func New(vfs virtualfs.FileSystem, resources *resources.Resources, loc *localization.Products) *YouTubeTakeout {
@@ -44,7 +67,8 @@ func New(vfs virtualfs.FileSystem, resources *resources.Resources, loc *localiza
 		resources:     resources,
 		channels:      make(map[string]Channel),
 		videosByID:    make(map[string]*Video),
-		videosByTitle: make(map[string]*Video),
+		videosByTitle: make(map[string][]*Video),
+		playlists:     make(map[string]*Playlist),
 		loc:           loc,
 	}
 }
@@ -69,6 +93,8 @@ func (to *YouTubeTakeout) Scan(ctx context.Context, filePath string, globalizedP
 			localizedPaths["video metadata"] = path.Join(filePath, d.Name())
 		case "videos":
 			localizedPaths["videos"] = path.Join(filePath, d.Name())
+		case "playlists": // missing from the localization data, known by its English name
+			localizedPaths["playlists"] = path.Join(filePath, d.Name())
 		}
 	}
 
@@ -86,6 +112,10 @@ func (to *YouTubeTakeout) Scan(ctx context.Context, filePath string, globalizedP
 	if err != nil {
 		return nil, err
 	}
+	err = to.scanPlaylists(ctx, to.vfs, localizedPaths["playlists"], path.Join(globalizedPath, "playlists"))
+	if err != nil {
+		return nil, err
+	}
 	return to, nil
 }
 
@@ -126,30 +156,75 @@ func (to *YouTubeTakeout) scanVideoMetadata(ctx context.Context, vfs virtualfs.F
 	if err != nil {
 		return err
 	}
+	tagFiles := []string{}
 	for _, f := range files {
 		if f.IsDir() {
 			continue
 		}
+		if resources.IsImage(f.Name()) {
+			to.thumbnails = append(to.thumbnails, path.Join(filePath, f.Name()))
+			continue
+		}
 		key, n := to.loc.Globalize(path.Join(globaliziedPath, f.Name()))
-		if key == "videos.csv" {
-			err = readCSV(ctx, vfs, path.Join(filePath, f.Name()), n, func(cols map[string]int, r []string) {
-				v := &Video{
-					ID:        r[cols["Video ID"]],
-					ChannelID: r[cols["Channel ID"]],
-					Title:     r[cols["Video Title (Original)"]],
-				}
-				if c, ok := to.channels[v.ChannelID]; ok {
-					c.Videos[v.ID] = v
-				}
-				to.videosByID[v.ID] = v
-				to.videosByTitle[v.Title] = v
-			})
+		switch key {
+		case "videos.csv":
+			err = readCSV(ctx, vfs, path.Join(filePath, f.Name()), n, to.addVideo)
+		case "video tags.csv": // missing from the localization data, known by its English name
+			tagFiles = append(tagFiles, path.Join(filePath, f.Name()))
 		}
+	}
 
+	// tags are given once all videos are known
+	for _, f := range tagFiles {
+		err = readCSV(ctx, vfs, f, nil, func(cols map[string]int, r []string) {
+			i, ok := cols["Video Tag (Original)"]
+			if !ok {
+				return
+			}
+			if v := to.videosByID[r[cols["Video ID"]]]; v != nil && r[i] != "" {
+				v.Tags = append(v.Tags, r[i])
+			}
+		})
+		if err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// addVideo records a row of the videos.csv file
+func (to *YouTubeTakeout) addVideo(cols map[string]int, r []string) {
+	v := &Video{
+		ID:        r[cols["Video ID"]],
+		ChannelID: r[cols["Channel ID"]],
+		Title:     r[cols["Video Title (Original)"]],
+	}
+	if i, ok := cols["Approx Duration (ms)"]; ok {
+		if ms, err := strconv.ParseInt(r[i], 10, 64); err == nil {
+			v.Duration = time.Duration(ms) * time.Millisecond
+		}
+	}
+	if i, ok := cols["Video Create Timestamp"]; ok {
+		v.Created = parseTimestamp(r[i])
+	}
+	if i, ok := cols["Video Publish Timestamp"]; ok {
+		v.Published = parseTimestamp(r[i])
+	}
+	if i, ok := cols["Video Description (Original)"]; ok {
+		v.Description = strings.TrimSpace(r[i])
+	}
+	if i, ok := cols["Privacy"]; ok {
+		v.Privacy = r[i]
+	}
+	if c, ok := to.channels[v.ChannelID]; ok {
+		c.Videos[v.ID] = v
+	}
+	to.videos = append(to.videos, v)
+	to.videosByID[v.ID] = v
+	key := titleKey(v.Title)
+	to.videosByTitle[key] = append(to.videosByTitle[key], v)
+}
+
 func (to *YouTubeTakeout) scanVideos(ctx context.Context, vfs virtualfs.FileSystem, filePath string) error {
 	if filePath == "" {
 		return fmt.Errorf("no 'videos' folder found in the YouTube Takeout. Check localization")
@@ -158,21 +233,117 @@ func (to *YouTubeTakeout) scanVideos(ctx context.Context, vfs virtualfs.FileSyst
 	if err != nil {
 		return err
 	}
+	videos := []fs.DirEntry{}
 	for _, f := range files {
-		if f.IsDir() {
+		switch {
+		case f.IsDir():
+			// thumbnails may be grouped in a sub folder
+			sub, err := fs.ReadDir(vfs, path.Join(filePath, f.Name()))
+			if err != nil {
+				return err
+			}
+			for _, s := range sub {
+				if !s.IsDir() && resources.IsImage(s.Name()) {
+					to.thumbnails = append(to.thumbnails, path.Join(filePath, f.Name(), s.Name()))
+				}
+			}
+		case resources.IsImage(f.Name()):
+			to.thumbnails = append(to.thumbnails, path.Join(filePath, f.Name()))
+		default:
+			videos = append(videos, f)
+		}
+	}
+	to.matchVideos(filePath, videos)
+	to.matchThumbnails()
+	return nil
+}
+
+// Open opens a file of the takeout, like a video thumbnail
+func (to *YouTubeTakeout) Open(name string) (fs.File, error) {
+	return to.vfs.Open(name)
+}
+
+// scanPlaylists reads the playlists.csv file, and the "<playlist title>-videos.csv" files listing the playlist's videos.
+// The playlists folder is optional.
+func (to *YouTubeTakeout) scanPlaylists(ctx context.Context, vfs virtualfs.FileSystem, filePath string, globaliziedPath string) error {
+	if filePath == "" {
+		return nil
+	}
+	files, err := fs.ReadDir(vfs, filePath)
+	if err != nil {
+		return err
+	}
+
+	byTitle := map[string]*Playlist{}
+	videoFiles := map[string]string{} // playlist title key -> CSV file
+	for _, f := range files {
+		if f.IsDir() || !strings.EqualFold(path.Ext(f.Name()), ".csv") {
+			continue
+		}
+		// files missing from the localization data keep their name
+		key, n := to.loc.Globalize(path.Join(globaliziedPath, f.Name()))
+		if key != "playlists.csv" {
+			title := strings.TrimSuffix(strings.TrimSuffix(f.Name(), path.Ext(f.Name())), "-videos")
+			videoFiles[titleKey(title)] = path.Join(filePath, f.Name())
 			continue
 		}
-		title := strings.TrimSuffix(f.Name(), path.Ext(f.Name()))
-		if v, ok := to.videosByTitle[title]; ok {
-			v.FileName = path.Join(filePath, f.Name())
-			r := to.resources.Add(to.vfs, v.ChannelID, filePath, f, nil)
-			v.Resource = r
-			to.videosByID[v.ID] = v
+		err = readCSV(ctx, vfs, path.Join(filePath, f.Name()), n, func(cols map[string]int, r []string) {
+			p := &Playlist{
+				ID:    r[cols["Playlist ID"]],
+				Title: r[cols["Playlist Title (Original)"]],
+			}
+			if i, ok := cols["Playlist Visibility"]; ok {
+				p.Visibility = r[i]
+			}
+			to.playlists[p.ID] = p
+			byTitle[titleKey(p.Title)] = p
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for key, file := range videoFiles {
+		p := byTitle[key]
+		if p == nil {
+			continue
+		}
+		err = readCSV(ctx, vfs, file, nil, func(cols map[string]int, r []string) {
+			if i, ok := cols["Video ID"]; ok && r[i] != "" {
+				p.VideoIDs = append(p.VideoIDs, strings.TrimSpace(r[i]))
+			}
+		})
+		if err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// Channels returns the channels of the takeout ordered by title
+func (to *YouTubeTakeout) Channels() []Channel {
+	l := slices.Collect(maps.Values(to.channels))
+	slices.SortFunc(l, func(a, b Channel) int {
+		return strings.Compare(a.Title, b.Title)
+	})
+	return l
+}
+
+// SearchPlaylist returns the playlist, nil when not found in the takeout
+func (to *YouTubeTakeout) SearchPlaylist(ctx context.Context, id string) *Playlist {
+	return to.playlists[id]
+}
+
 func (to *YouTubeTakeout) SearchByID(ctx context.Context, id string) *Video {
 	return to.videosByID[id]
 }
+
+// parseTimestamp reads the timestamps of the CSV files, the zero time when invalid
+func parseTimestamp(s string) time.Time {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02 15:04:05 MST", "2006-01-02 15:04:05"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
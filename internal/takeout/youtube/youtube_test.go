@@ -0,0 +1,197 @@
+package youtube
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"path"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"bloggerout/internal/takeout/resources"
+
+	"github.com/simulot/TakeoutLocalization/go/localization"
+)
+
+// newMP4 builds a minimal MP4 file having the given duration
+func newMP4(d time.Duration) []byte {
+	box := func(typ string, payload []byte) []byte {
+		b := binary.BigEndian.AppendUint32(nil, uint32(8+len(payload)))
+		return append(append(b, typ...), payload...)
+	}
+	mvhd := make([]byte, 20)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[16:20], uint32(d.Milliseconds()))
+	var b bytes.Buffer
+	b.Write(box("ftyp", []byte("isom")))
+	b.Write(box("moov", box("mvhd", mvhd)))
+	b.Write(box("mdat", make([]byte, 64)))
+	return b.Bytes()
+}
+
+func TestMP4Duration(t *testing.T) {
+	d, err := mp4Duration(bytes.NewReader(newMP4(83 * time.Second)))
+	if err != nil || d != 83*time.Second {
+		t.Errorf("mp4Duration() = %v, %v; want 83s", d, err)
+	}
+	_, err = mp4Duration(bytes.NewReader([]byte("not a video")))
+	if err == nil {
+		t.Errorf("mp4Duration() expected an error")
+	}
+}
+
+func TestMatchVideos(t *testing.T) {
+	created := time.Date(2019, 7, 14, 10, 0, 0, 0, time.UTC)
+	vfs := fstest.MapFS{
+		"videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Approx Duration (ms),Channel ID,Video Title (Original),Video Create Timestamp
+id1,20000,ch,Holidays,2019-07-14T10:00:00+00:00
+id2,10000,ch,Holidays,2019-07-15T10:00:00+00:00
+id3,0,ch,"What? Yes: no/maybe",2019-07-16T10:00:00+00:00
+id4,0,ch,A very long title that was truncated by the takeout,2019-07-17T10:00:00+00:00
+id5,0,ch,Lost video,2019-07-18T10:00:00+00:00
+id6,0,ch,Birthday,2020-01-01T10:00:00+00:00
+id7,0,ch,Birthday,2020-06-01T10:00:00+00:00
+`)},
+		"videos/Holidays.mp4":                     &fstest.MapFile{Data: newMP4(10 * time.Second)},
+		"videos/Holidays(1).mp4":                  &fstest.MapFile{Data: newMP4(20 * time.Second)},
+		"videos/What_ Yes_ no_maybe.mp4":          &fstest.MapFile{Data: []byte("video")},
+		"videos/A very long title that w.mp4":     &fstest.MapFile{Data: []byte("video")},
+		"videos/Birthday.mp4":                     &fstest.MapFile{Data: []byte("video"), ModTime: created.AddDate(0, 11, 0)},
+		"videos/Birthday(1).mp4":                  &fstest.MapFile{Data: []byte("video"), ModTime: created.AddDate(0, 6, 0)},
+		"videos/Unknown.mp4":                      &fstest.MapFile{Data: []byte("video")},
+		"videos/Short.mp4":                        &fstest.MapFile{Data: []byte("video")},
+		"videos/A very long title that was t.mp4": &fstest.MapFile{Data: []byte("video")},
+	}
+
+	to := New(vfs, resources.New(), nil)
+	err := readCSV(context.Background(), vfs, "videos.csv", nil, to.addVideo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = to.scanVideos(context.Background(), vfs, "videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"id1": "Holidays(1).mp4",
+		"id2": "Holidays.mp4",
+		"id3": "What_ Yes_ no_maybe.mp4",
+		"id4": "A very long title that was t.mp4",
+		"id5": "",
+		"id6": "Birthday(1).mp4",
+		"id7": "Birthday.mp4",
+	}
+	for id, want := range expected {
+		v := to.SearchByID(context.Background(), id)
+		got := ""
+		if v.Resource != nil {
+			got = path.Base(v.FileName)
+		}
+		if got != want {
+			t.Errorf("video %s matched %q; want %q", id, got, want)
+		}
+	}
+
+	files, videos := to.Unmatched()
+	wantFiles := []string{"videos/A very long title that w.mp4", "videos/Short.mp4", "videos/Unknown.mp4"}
+	if len(files) != len(wantFiles) {
+		t.Fatalf("unmatched files = %v; want %v", files, wantFiles)
+	}
+	for i := range files {
+		if files[i] != wantFiles[i] {
+			t.Errorf("unmatched files = %v; want %v", files, wantFiles)
+			break
+		}
+	}
+	if len(videos) != 1 || videos[0].ID != "id5" {
+		t.Errorf("unmatched videos = %v; want id5", videos)
+	}
+}
+
+func TestVideoMetadata(t *testing.T) {
+	vfs := fstest.MapFS{
+		"videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Approx Duration (ms),Video Description (Original),Channel ID,Video Title (Original),Privacy,Video Create Timestamp,Video Publish Timestamp
+id1,83000,"At the beach
+with Paul",ch,Holidays,Public,2019-07-14T10:00:00+00:00,2019-07-15T08:30:00+00:00
+id2,0,,ch,Birthday,Private,2020-01-01T10:00:00+00:00,
+id3,0,,ch,Garden,Unlisted,2020-05-01T10:00:00+00:00,
+`)},
+		"videos/Holidays.mp4":           &fstest.MapFile{Data: []byte("video")},
+		"videos/Birthday.mp4":           &fstest.MapFile{Data: []byte("video")},
+		"videos/Garden.mp4":             &fstest.MapFile{Data: []byte("video")},
+		"videos/thumbnails/id1.jpg":     &fstest.MapFile{Data: []byte("jpeg")},
+		"videos/Birthday.jpg":           &fstest.MapFile{Data: []byte("jpeg")},
+		"videos/thumbnails/Unknown.jpg": &fstest.MapFile{Data: []byte("jpeg")},
+	}
+	to := New(vfs, resources.New(), nil)
+	err := readCSV(context.Background(), vfs, "videos.csv", nil, to.addVideo)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = to.scanVideos(context.Background(), vfs, "videos")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := to.SearchByID(context.Background(), "id1")
+	if v.Description != "At the beach\nwith Paul" || v.Privacy != "Public" || v.Duration != 83*time.Second {
+		t.Errorf("unexpected metadata: %+v", v)
+	}
+	if !v.Created.Equal(time.Date(2019, 7, 14, 10, 0, 0, 0, time.UTC)) || !v.Published.Equal(time.Date(2019, 7, 15, 8, 30, 0, 0, time.UTC)) {
+		t.Errorf("unexpected dates: created %v, published %v", v.Created, v.Published)
+	}
+
+	for id, want := range map[string]string{"id1": "videos/thumbnails/id1.jpg", "id2": "videos/Birthday.jpg", "id3": ""} {
+		v := to.SearchByID(context.Background(), id)
+		if v.Thumbnail != want {
+			t.Errorf("video %s thumbnail = %q; want %q", id, v.Thumbnail, want)
+		}
+		if v.Resource == nil {
+			t.Errorf("video %s has no file", id)
+		}
+	}
+	if v := to.SearchByID(context.Background(), "id2"); !v.Published.IsZero() {
+		t.Errorf("video id2 published %v; want zero", v.Published)
+	}
+}
+
+func TestScanPlaylists(t *testing.T) {
+	vfs := fstest.MapFS{
+		"YouTube/channels/channel.csv": &fstest.MapFile{Data: []byte("Channel ID,Channel Title (Original)\nch,Family\n")},
+		"YouTube/video metadata/videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Channel ID,Video Title (Original)
+id1,ch,Holidays
+id2,ch,Birthday
+`)},
+		"YouTube/videos/Holidays.mp4": &fstest.MapFile{Data: []byte("video")},
+		"YouTube/videos/Birthday.mp4": &fstest.MapFile{Data: []byte("video")},
+		"YouTube/playlists/playlists.csv": &fstest.MapFile{Data: []byte(`Playlist ID,Playlist Title (Original),Playlist Visibility
+PL123,Summer 2019,Public
+PL456,Empty,Private
+`)},
+		"YouTube/playlists/Summer 2019-videos.csv": &fstest.MapFile{Data: []byte(`Video ID,Playlist Video Creation Timestamp
+id2,2019-07-14T10:00:00+00:00
+idX,2019-07-14T10:00:00+00:00
+id1,2019-07-14T10:00:00+00:00
+`)},
+	}
+	to, err := New(vfs, resources.New(), &localization.Products{}).Scan(context.Background(), "YouTube", "YouTube and YouTube Music")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := to.SearchPlaylist(context.Background(), "PL123")
+	if p == nil {
+		t.Fatal("playlist PL123 not found")
+	}
+	if p.Title != "Summer 2019" || p.Visibility != "Public" || strings.Join(p.VideoIDs, ",") != "id2,idX,id1" {
+		t.Errorf("unexpected playlist: %+v", p)
+	}
+	if p := to.SearchPlaylist(context.Background(), "PL456"); p == nil || len(p.VideoIDs) != 0 {
+		t.Errorf("unexpected playlist PL456: %+v", p)
+	}
+	if p := to.SearchPlaylist(context.Background(), "PL789"); p != nil {
+		t.Errorf("unexpected playlist PL789: %+v", p)
+	}
+}